@@ -0,0 +1,85 @@
+package dejavu
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	BackoffJitter     = 0.2
+	BackoffMultiplier = 2
+)
+
+type Backoff interface {
+	fmt.Stringer
+
+	Next(attempt int) time.Duration
+}
+
+func NewFixedBackoff(interval time.Duration) FixedBackoff {
+	return FixedBackoff{interval: interval}
+}
+
+type FixedBackoff struct {
+	interval time.Duration
+}
+
+func (b FixedBackoff) Next(int) time.Duration {
+	return b.interval
+}
+
+func (b FixedBackoff) String() string {
+	return fmt.Sprintf("fixed backoff of %v", b.interval)
+}
+
+func NewExponentialBackoff(initial, maxInterval time.Duration) ExponentialBackoff {
+	return ExponentialBackoff{
+		initial:     initial,
+		jitter:      BackoffJitter,
+		maxInterval: maxInterval,
+		multiplier:  BackoffMultiplier,
+	}
+}
+
+type ExponentialBackoff struct {
+	initial     time.Duration
+	jitter      float64
+	maxInterval time.Duration
+	multiplier  float64
+}
+
+func (b ExponentialBackoff) WithJitter(value float64) ExponentialBackoff {
+	b.jitter = value
+
+	return b
+}
+
+func (b ExponentialBackoff) WithMultiplier(value float64) ExponentialBackoff {
+	b.multiplier = value
+
+	return b
+}
+
+func (b ExponentialBackoff) Next(attempt int) time.Duration {
+	interval := math.Min(
+		float64(b.initial)*math.Pow(b.multiplier, float64(attempt)),
+		float64(b.maxInterval),
+	)
+
+	if b.jitter > 0 {
+		interval += interval * b.jitter * (2*rand.Float64() - 1) //nolint:gosec
+	}
+
+	return min(time.Duration(interval), b.maxInterval)
+}
+
+func (b ExponentialBackoff) String() string {
+	return fmt.Sprintf("exponential backoff from %v to %v (x%v, jitter %v)",
+		b.initial,
+		b.maxInterval,
+		b.multiplier,
+		b.jitter,
+	)
+}
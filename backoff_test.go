@@ -0,0 +1,38 @@
+package dejavu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedBackoff_Next(t *testing.T) {
+	backoff := NewFixedBackoff(time.Second)
+
+	for attempt := 0; attempt < 10; attempt++ {
+		assert.Equal(t, time.Second, backoff.Next(attempt))
+	}
+}
+
+func TestExponentialBackoff_Next(t *testing.T) {
+	backoff := NewExponentialBackoff(time.Second, time.Minute).WithJitter(0)
+
+	assert.Equal(t, time.Second, backoff.Next(0))
+	assert.Equal(t, 2*time.Second, backoff.Next(1))
+	assert.Equal(t, 4*time.Second, backoff.Next(2))
+	assert.Equal(t, time.Minute, backoff.Next(10))
+	assert.Equal(t, 27*time.Second, backoff.WithMultiplier(3).Next(3))
+}
+
+func TestExponentialBackoff_Next_Jitter(t *testing.T) {
+	backoff := NewExponentialBackoff(time.Second, time.Minute)
+
+	for i := 0; i < 100; i++ {
+		next := backoff.Next(2)
+
+		assert.GreaterOrEqual(t, next, 3200*time.Millisecond)
+		assert.LessOrEqual(t, next, 4800*time.Millisecond)
+		assert.LessOrEqual(t, backoff.Next(10), time.Minute)
+	}
+}
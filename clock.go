@@ -24,3 +24,42 @@ func (c UtcClock) Now() time.Time {
 func (c UtcClock) String() string {
 	return "UTC Clock"
 }
+
+type dbTime struct {
+	time.Time
+}
+
+func (t *dbTime) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		t.Time = v
+
+		return nil
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	case nil:
+		t.Time = time.Time{}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported time %T: %v", value, value)
+}
+
+func (t *dbTime) parse(value string) error {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		time.RFC3339Nano,
+	} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported time format: %s", value)
+}
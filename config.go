@@ -2,29 +2,46 @@ package dejavu
 
 import (
 	"fmt"
+	"os"
 	"time"
 )
 
 const (
-	TickInterval = 5 * time.Second
-	Timeout      = 5 * time.Minute
+	DefaultNamespace  = "default"
+	HeartbeatInterval = 30 * time.Second
+	LockTTL           = 2 * time.Minute
+	TickInterval      = 5 * time.Second
+	Timeout           = 5 * time.Minute
+	CleanupTimeout    = 30 * time.Second
 )
 
 type Config struct {
-	clock   Clock
-	db      Database
-	logger  Logger
-	migs    Migrations
-	tick    time.Duration
-	timeout time.Duration
+	aliases   map[string]string
+	backoff   Backoff
+	clock     Clock
+	data      any
+	db        Database
+	heartbeat time.Duration
+	locker    Locker
+	logger    Logger
+	migs      Migrations
+	ns        string
+	signals   []os.Signal
+	tick      time.Duration
+	timeout   time.Duration
+	ttl       time.Duration
+	version   string
 }
 
 func NewConfig(db Database, migs Migrations) *Config {
 	return &Config{
-		db:      db,
-		migs:    migs,
-		tick:    TickInterval,
-		timeout: Timeout,
+		db:        db,
+		heartbeat: HeartbeatInterval,
+		migs:      migs,
+		ns:        DefaultNamespace,
+		tick:      TickInterval,
+		timeout:   Timeout,
+		ttl:       LockTTL,
 	}
 }
 
@@ -35,6 +52,14 @@ func (c *Config) Build() DejaVu {
 		cfg.clock = NewUtcClock()
 	}
 
+	if cfg.backoff == nil {
+		cfg.backoff = NewFixedBackoff(cfg.tick)
+	}
+
+	if cfg.locker == nil {
+		cfg.locker = cfg.db
+	}
+
 	if cfg.logger == nil {
 		cfg.logger = LogLogger{}
 	}
@@ -44,18 +69,66 @@ func (c *Config) Build() DejaVu {
 	return DejaVu{Config: cfg}
 }
 
+func (c *Config) WithAliases(aliases map[string]string) *Config {
+	c.aliases = aliases
+
+	return c
+}
+
+func (c *Config) WithBackoff(backoff Backoff) *Config {
+	c.backoff = backoff
+
+	return c
+}
+
 func (c *Config) WithClock(clock Clock) *Config {
 	c.clock = clock
 
 	return c
 }
 
+func (c *Config) WithData(data any) *Config {
+	c.data = data
+
+	return c
+}
+
+func (c *Config) WithHeartbeat(value time.Duration) *Config {
+	c.heartbeat = value
+
+	return c
+}
+
+func (c *Config) WithLocker(locker Locker) *Config {
+	c.locker = locker
+
+	return c
+}
+
+func (c *Config) WithLockTTL(value time.Duration) *Config {
+	c.ttl = value
+
+	return c
+}
+
 func (c *Config) WithLogger(logger Logger) *Config {
 	c.logger = logger
 
 	return c
 }
 
+func (c *Config) WithNamespace(value string) *Config {
+	c.ns = value
+
+	return c
+}
+
+func (c *Config) WithSignals(signals ...os.Signal) *Config {
+	c.signals = signals
+
+	return c
+}
+
 func (c *Config) WithTick(value time.Duration) *Config {
 	c.tick = value
 
@@ -68,12 +141,23 @@ func (c *Config) WithTimeout(value time.Duration) *Config {
 	return c
 }
 
+func (c *Config) WithVersion(value string) *Config {
+	c.version = value
+
+	return c
+}
+
 func (c *Config) String() string {
-	return fmt.Sprintf("Config: clock=%v, db=%v, migs=%v, tick=%v, timeout=%v",
+	return fmt.Sprintf(
+		"Config: clock=%v, db=%v, heartbeat=%v, migs=%v, namespace=%s, tick=%v, timeout=%v, ttl=%v, version=%s",
 		c.clock,
 		c.db,
+		c.heartbeat,
 		c.migs,
+		c.ns,
 		c.tick,
 		c.timeout,
+		c.ttl,
+		c.version,
 	)
 }
@@ -2,6 +2,7 @@ package dejavu
 
 import (
 	"database/sql"
+	"os"
 	"testing"
 	"time"
 
@@ -16,13 +17,26 @@ func newTestConfig(t *testing.T, db *sql.DB, name string, placeholders Placehold
 	logger := newTestLogger(t)
 
 	return NewConfig(
-		NewDatabase(clock, logger, name, NewRepository(db, logger, placeholders), DefaultStatements{}),
+		NewDatabase(clock, logger, name, NewRepository(db, logger, placeholders), newTestStatements(name)),
 		newTestMigrations(t),
 	).
 		WithClock(clock).
 		WithLogger(logger)
 }
 
+func newTestStatements(name string) Statements {
+	switch name {
+	case "mysql":
+		return NewMySQLStatements(DefaultStatements{})
+	case "postgresql":
+		return NewPostgreSQLStatements(DefaultStatements{})
+	case "sqlite":
+		return NewSQLiteStatements(DefaultStatements{})
+	}
+
+	return DefaultStatements{}
+}
+
 func TestNewConfig(t *testing.T) {
 	logger := newTestLogger(t)
 	db := NewDatabase(
@@ -40,8 +54,11 @@ func TestNewConfig(t *testing.T) {
 	assert.Equal(t, db, cfg.db)
 	assert.Nil(t, cfg.logger)
 	assert.Equal(t, migs, cfg.migs)
+	assert.Equal(t, DefaultNamespace, cfg.ns)
 	assert.Equal(t, TickInterval, cfg.tick)
 	assert.Equal(t, Timeout, cfg.timeout)
+	assert.Equal(t, HeartbeatInterval, cfg.heartbeat)
+	assert.Equal(t, LockTTL, cfg.ttl)
 }
 
 func TestConfig_Build(t *testing.T) {
@@ -59,6 +76,8 @@ func TestConfig_Build(t *testing.T) {
 	assert.NotNil(t, dv.clock)
 	assert.IsType(t, UtcClock{}, dv.clock)
 	assert.Equal(t, db, dv.db)
+	assert.Equal(t, db, dv.locker)
+	assert.Equal(t, NewFixedBackoff(TickInterval), dv.backoff)
 	assert.NotNil(t, dv.logger)
 	assert.IsType(t, LogLogger{}, dv.logger)
 	assert.Equal(t, migs, dv.migs)
@@ -66,6 +85,40 @@ func TestConfig_Build(t *testing.T) {
 	assert.Equal(t, Timeout, dv.timeout)
 }
 
+func TestConfig_WithAliases(t *testing.T) {
+	logger := newTestLogger(t)
+	aliases := map[string]string{"01_create_contry_table.sql": "01_create_country_table.sql"}
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithAliases(aliases)
+
+	assert.Equal(t, aliases, cfg.aliases)
+}
+
+func TestConfig_WithBackoff(t *testing.T) {
+	logger := newTestLogger(t)
+	backoff := NewExponentialBackoff(time.Second, time.Minute)
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithBackoff(backoff)
+
+	assert.Equal(t, backoff, cfg.backoff)
+}
+
 func TestConfig_WithClock(t *testing.T) {
 	logger := newTestLogger(t)
 	testClock := newTestClock()
@@ -83,6 +136,58 @@ func TestConfig_WithClock(t *testing.T) {
 	assert.Equal(t, testClock, cfg.clock)
 }
 
+func TestConfig_WithHeartbeat(t *testing.T) {
+	logger := newTestLogger(t)
+	heartbeat := 42 * time.Second
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithHeartbeat(heartbeat)
+
+	assert.Equal(t, heartbeat, cfg.heartbeat)
+}
+
+func TestConfig_WithLocker(t *testing.T) {
+	logger := newTestLogger(t)
+	locker, err := NewSQLiteLocker("sqlite", "deja_vu.lock", logger)
+	require.NoError(t, err)
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithLocker(locker)
+
+	assert.Equal(t, locker, cfg.locker)
+}
+
+func TestConfig_WithLockTTL(t *testing.T) {
+	logger := newTestLogger(t)
+	ttl := 42 * time.Minute
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithLockTTL(ttl)
+
+	assert.Equal(t, ttl, cfg.ttl)
+}
+
 func TestConfig_WithLogger(t *testing.T) {
 	logger := newTestLogger(t)
 	cfg := NewConfig(
@@ -99,6 +204,42 @@ func TestConfig_WithLogger(t *testing.T) {
 	assert.Equal(t, logger, cfg.logger)
 }
 
+func TestConfig_WithNamespace(t *testing.T) {
+	logger := newTestLogger(t)
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithNamespace("billing")
+
+	assert.Equal(t, "billing", cfg.ns)
+}
+
+func TestConfig_WithSignals(t *testing.T) {
+	logger := newTestLogger(t)
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	)
+
+	assert.Empty(t, cfg.signals)
+
+	cfg.WithSignals(os.Interrupt)
+
+	assert.Equal(t, []os.Signal{os.Interrupt}, cfg.signals)
+}
+
 func TestConfig_WithTick(t *testing.T) {
 	logger := newTestLogger(t)
 	tick := 42 * time.Minute
@@ -133,6 +274,22 @@ func TestConfig_WithTimeout(t *testing.T) {
 	assert.Equal(t, timeout, cfg.timeout)
 }
 
+func TestConfig_WithVersion(t *testing.T) {
+	logger := newTestLogger(t)
+	cfg := NewConfig(
+		NewDatabase(
+			newTestClock(),
+			logger,
+			"",
+			NewRepository(nil, logger, PlaceholdersQuestionMark()),
+			DefaultStatements{},
+		),
+		newTestMigrations(t),
+	).WithVersion("1.2.3")
+
+	assert.Equal(t, "1.2.3", cfg.version)
+}
+
 func TestConfig_String(t *testing.T) {
 	assert.Equal(
 		t,
@@ -142,10 +299,14 @@ func TestConfig_String(t *testing.T) {
 			"clock=Fixed clock at 2023-03-10 22:04:27 +0000 UTC, "+
 			"logger=test logger, "+
 			"repo=SQL db with Question Mark args with ?, "+
-			"stmts=Default SQL statements, "+
+			"stmts=MySQL SQL statements, "+
+			"heartbeat=30s, "+
 			"migs=&{testdata db}, "+
+			"namespace=default, "+
 			"tick=5s, "+
-			"timeout=5m0s",
+			"timeout=5m0s, "+
+			"ttl=2m0s, "+
+			"version=",
 		newTestConfig(t, nil, "mysql", PlaceholdersQuestionMark()).String(),
 	)
 }
@@ -3,22 +3,26 @@ package dejavu
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 type Database interface {
-	fmt.Stringer
+	Locker
 
 	Name() string
 
 	Init(ctx context.Context) error
 
-	Lock(ctx context.Context, lck Lock) bool
+	LockStatus(ctx context.Context, lck Lock) (Lock, bool, error)
+	ForceUnlock(ctx context.Context, lck Lock) (Lock, bool, error)
 
-	History(ctx context.Context) ([]Migration, error)
-	Migrate(ctx context.Context, name, content string) error
-
-	Unlock(ctx context.Context, lck Lock) error
+	History(ctx context.Context, namespace string) ([]Migration, error)
+	Migrate(ctx context.Context, mig Migration, content string) error
+	Rename(ctx context.Context, namespace string, names map[string]string) error
 }
 
 func NewDatabase(clock Clock, logger Logger, name string, repo Repository, stmts Statements) DefaultDatabase {
@@ -67,30 +71,233 @@ func (d DefaultDatabase) Count(ctx context.Context, table string) (int, error) {
 	return result, err
 }
 
-func (d DefaultDatabase) Exist(ctx context.Context, table string) bool {
-	_, err := d.Count(ctx, table)
+func (d DefaultDatabase) Exist(ctx context.Context, table string) (bool, error) {
+	stmt := d.stmts.TableExists(table)
+	if stmt == nil {
+		return d.HasColumn(ctx, table, "1"), nil
+	}
 
-	return err == nil
+	var count int
+
+	err := d.repo.EnsureTransaction(ctx, d.ReadOnlyTx(), func(ctx context.Context, repo Repository) error {
+		return repo.QueryRow(ctx, stmt).Scan(&count)
+	})
+	if err != nil {
+		return false, newError(err, "failed to check if table %s exists", table)
+	}
+
+	return count > 0, nil
 }
 
-func (d DefaultDatabase) Init(ctx context.Context) error {
+func (d DefaultDatabase) InitLock(ctx context.Context) error {
 	if err := d.Ping(ctx); err != nil {
 		return err
 	}
 
-	if err := d.InitLockTable(ctx); err != nil {
+	return d.InitLockTable(ctx)
+}
+
+func (d DefaultDatabase) Init(ctx context.Context) error {
+	version, err := d.MetadataVersion(ctx)
+	if err != nil {
 		return err
 	}
 
-	if err := d.InitHistoryTable(ctx); err != nil {
+	if version > MetadataVersion {
+		return newError(nil, "metadata version %d is newer than supported version %d", version, MetadataVersion)
+	}
+
+	if version < 0 {
+		if err = d.InitMetadataTable(ctx, MetadataVersion); err != nil {
+			return err
+		}
+
+		version = MetadataVersion
+	}
+
+	for ; version < MetadataVersion; version++ {
+		if err = d.UpgradeMetadata(ctx, version); err != nil {
+			return err
+		}
+	}
+
+	return d.InitHistoryTable(ctx)
+}
+
+func (d DefaultDatabase) MetadataVersion(ctx context.Context) (int, error) {
+	exist, err := d.Exist(ctx, d.stmts.MetadataTable())
+	if err != nil {
+		return 0, err
+	}
+
+	if !exist {
+		if exist, err = d.Exist(ctx, d.stmts.HistoryTable()); err != nil {
+			return 0, err
+		}
+
+		if !exist {
+			return -1, nil
+		}
+
+		if err = d.InitMetadataTable(ctx, 0); err != nil {
+			return 0, err
+		}
+
+		return 0, nil
+	}
+
+	var result sql.NullInt64
+
+	err = d.repo.EnsureTransaction(ctx, d.ReadOnlyTx(), func(ctx context.Context, repo Repository) error {
+		if err := repo.QueryRow(ctx, d.stmts.MetadataVersion()).Scan(&result); err != nil {
+			return newError(err, "failed to find metadata version")
+		}
+
+		return nil
+	})
+
+	return int(result.Int64), err
+}
+
+func (d DefaultDatabase) InitMetadataTable(ctx context.Context, version int) error {
+	d.logger.Log("Creating metadata table...")
+
+	if err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		if err := repo.Exec(ctx, d.stmts.CreateMetadataTable()); err != nil {
+			return err
+		}
+
+		return repo.Exec(ctx, d.stmts.InsertMetadataVersion(version))
+	}); err != nil {
+		return newError(err, "failed to create metadata table")
+	}
+
+	d.logger.Log(fmt.Sprintf("Metadata table successfully created with version %d", version))
+
+	return nil
+}
+
+func (d DefaultDatabase) UpgradeMetadata(ctx context.Context, from int) error {
+	d.logger.Log(fmt.Sprintf("Upgrading metadata from version %d to %d...", from, from+1))
+
+	stmts := d.skipExistingColumns(ctx, d.stmts.UpgradeMetadata(from))
+
+	if from == 1 {
+		var err error
+
+		if stmts, err = d.resumeHistoryRebuild(ctx, stmts); err != nil {
+			return err
+		}
+	}
+
+	if err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		for _, stmt := range stmts {
+			if err := repo.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		if err := d.backfillHistory(ctx, repo); err != nil {
+			return err
+		}
+
+		return repo.Exec(ctx, d.stmts.UpdateMetadataVersion(from+1))
+	}); err != nil {
+		return newError(err, "failed to upgrade metadata from version %d", from)
+	}
+
+	d.logger.Log(fmt.Sprintf("Metadata successfully upgraded to version %d", from+1))
+
+	return nil
+}
+
+func (d DefaultDatabase) skipExistingColumns(ctx context.Context, stmts []*Statement) []*Statement {
+	result := make([]*Statement, 0, len(stmts))
+
+	for _, stmt := range stmts {
+		if stmt.column != "" && d.HasColumn(ctx, d.stmts.HistoryTable(), stmt.column) {
+			d.logger.Log(fmt.Sprintf("Column %s already exists in history table", stmt.column))
+
+			continue
+		}
+
+		result = append(result, stmt)
+	}
+
+	return result
+}
+
+func (d DefaultDatabase) resumeHistoryRebuild(ctx context.Context, stmts []*Statement) ([]*Statement, error) {
+	backup, err := d.Exist(ctx, d.stmts.HistoryBackupTable())
+	if err != nil || !backup {
+		return stmts, err
+	}
+
+	history, err := d.Exist(ctx, d.stmts.HistoryTable())
+	if err != nil {
+		return nil, err
+	}
+
+	d.logger.Log(fmt.Sprintf("Resuming history rebuild from %s...", d.stmts.HistoryBackupTable()))
+
+	return d.stmts.RestoreHistory(history), nil
+}
+
+func (d DefaultDatabase) backfillHistory(ctx context.Context, repo Repository) error {
+	var names []string
+
+	rows, err := repo.Query(ctx, d.stmts.UnrankedHistory())
+	if err != nil {
 		return err
 	}
 
+	for rows.Next() {
+		var name string
+
+		if err = rows.Scan(&name); err != nil {
+			_ = rows.Close()
+
+			return err
+		}
+
+		names = append(names, name)
+	}
+
+	if err = rows.Close(); err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	rank, err := d.nextInstalledRank(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		mig := Migration{
+			Name:          name,
+			InstalledRank: rank + i,
+			Description:   Describe(name),
+		}
+
+		if err = repo.Exec(ctx, d.stmts.BackfillHistory(mig)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (d DefaultDatabase) InitLockTable(ctx context.Context) error {
-	if !d.Exist(ctx, LockTableName) {
+	exist, err := d.Exist(ctx, d.stmts.LockTable())
+	if err != nil {
+		return err
+	}
+
+	if !exist {
 		d.logger.Log("Creating lock table...")
 
 		if err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
@@ -100,16 +307,50 @@ func (d DefaultDatabase) InitLockTable(ctx context.Context) error {
 		}
 
 		d.logger.Log("Lock table successfully created")
+	} else if !d.HasColumn(ctx, d.stmts.LockTable(), LockColumnToken) {
+		d.logger.Log("Upgrading lock table...")
+
+		if err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+			for _, stmt := range d.stmts.UpgradeLockTable() {
+				if err := repo.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil && !d.HasColumn(ctx, d.stmts.LockTable(), LockColumnToken) {
+			return newError(err, "failed to upgrade lock table")
+		}
+
+		d.logger.Log("Lock table successfully upgraded")
 	}
 
 	return nil
 }
 
+func (d DefaultDatabase) HasColumn(ctx context.Context, table, column string) bool {
+	err := d.repo.EnsureTransaction(ctx, d.ReadOnlyTx(), func(ctx context.Context, repo Repository) error {
+		rows, err := repo.Query(ctx, d.stmts.ProbeColumn(table, column))
+		if err != nil {
+			return err
+		}
+
+		return rows.Close()
+	})
+
+	return err == nil
+}
+
 func (d DefaultDatabase) InitHistoryTable(ctx context.Context) error {
-	if !d.Exist(ctx, HistoryTableName) {
+	exist, err := d.Exist(ctx, d.stmts.HistoryTable())
+	if err != nil {
+		return err
+	}
+
+	if !exist {
 		d.logger.Log("Creating history table...")
 
-		err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		err = d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
 			return repo.Exec(ctx, d.stmts.CreateHistoryTable())
 		})
 		if err != nil {
@@ -137,13 +378,152 @@ func (d DefaultDatabase) Lock(ctx context.Context, lck Lock) bool {
 	return false
 }
 
-func (d DefaultDatabase) History(ctx context.Context) ([]Migration, error) {
+func (d DefaultDatabase) Refresh(ctx context.Context, lck Lock) error {
+	var count int64
+
+	err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		var err error
+
+		count, err = repo.ExecAffected(ctx, d.stmts.RefreshLock(lck))
+
+		return err
+	})
+	if err != nil {
+		return newError(err, "failed to refresh lock")
+	}
+
+	if count == 0 {
+		return newError(ErrLockLost, "failed to refresh lock")
+	}
+
+	return nil
+}
+
+func (d DefaultDatabase) Expire(ctx context.Context, lck Lock, ttl time.Duration) (Lock, bool, error) {
+	var (
+		holder  Lock
+		expired bool
+	)
+
+	err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		var (
+			found bool
+			err   error
+		)
+
+		holder, found, err = d.readLock(ctx, repo, lck)
+		if err != nil || !found {
+			return err
+		}
+
+		now, err := d.now(ctx, repo)
+		if err != nil {
+			return err
+		}
+
+		count, err := repo.ExecAffected(ctx, d.stmts.ExpireLock(lck, now.Add(-ttl)))
+		if err != nil {
+			return err
+		}
+
+		expired = count > 0
+
+		return nil
+	})
+	if err != nil {
+		return holder, false, newError(err, "failed to expire lock")
+	}
+
+	return holder, expired, nil
+}
+
+func (d DefaultDatabase) LockStatus(ctx context.Context, lck Lock) (Lock, bool, error) {
+	var (
+		holder Lock
+		found  bool
+	)
+
+	err := d.repo.EnsureTransaction(ctx, d.ReadOnlyTx(), func(ctx context.Context, repo Repository) error {
+		var err error
+
+		holder, found, err = d.readLock(ctx, repo, lck)
+
+		return err
+	})
+	if err != nil {
+		return holder, false, newError(err, "failed to read lock")
+	}
+
+	return holder, found, nil
+}
+
+func (d DefaultDatabase) ForceUnlock(ctx context.Context, lck Lock) (Lock, bool, error) {
+	var (
+		holder Lock
+		found  bool
+	)
+
+	err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		var err error
+
+		holder, found, err = d.readLock(ctx, repo, lck)
+		if err != nil || !found {
+			return err
+		}
+
+		count, err := repo.ExecAffected(ctx, d.stmts.ForceUnlock(lck))
+		found = count > 0
+
+		return err
+	})
+	if err != nil {
+		return holder, false, newError(err, "failed to force unlock")
+	}
+
+	return holder, found, nil
+}
+
+func (d DefaultDatabase) readLock(ctx context.Context, repo Repository, lck Lock) (Lock, bool, error) {
+	var since dbTime
+
+	result := lck
+
+	err := repo.QueryRow(ctx, d.stmts.ReadLock(lck)).Scan(&result.hostname, &result.pid, &since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, false, nil
+	} else if err != nil {
+		return result, false, err
+	}
+
+	result.since = since.Time
+
+	now, err := d.now(ctx, repo)
+	if err != nil {
+		return result, true, err
+	}
+
+	result.age = now.Sub(result.since)
+
+	return result, true, nil
+}
+
+func (d DefaultDatabase) now(ctx context.Context, repo Repository) (time.Time, error) {
+	var result dbTime
+
+	if err := repo.QueryRow(ctx, d.stmts.CurrentTimestamp()).Scan(&result); err != nil {
+		return result.Time, newError(err, "failed to read database clock")
+	}
+
+	return result.Time, nil
+}
+
+func (d DefaultDatabase) History(ctx context.Context, namespace string) ([]Migration, error) {
 	d.logger.Log("Finding existing migrations...")
 
 	var migs []Migration
 
 	err := d.repo.EnsureTransaction(ctx, d.ReadOnlyTx(), func(ctx context.Context, _ Repository) error {
-		rows, err := d.repo.Query(ctx, d.stmts.History())
+		rows, err := d.repo.Query(ctx, d.stmts.History(namespace))
 		if err != nil {
 			return newError(err, "failed to query database history")
 		}
@@ -151,12 +531,12 @@ func (d DefaultDatabase) History(ctx context.Context) ([]Migration, error) {
 		defer rows.Close()
 
 		for rows.Next() {
-			var mig Migration
-
-			if err = rows.Scan(&mig.Name, &mig.Start, &mig.DurationMs, &mig.Checksum); err != nil {
-				return newError(err, "failed to scan database history")
+			mig, err := d.scanHistory(rows)
+			if err != nil {
+				return err
 			}
 
+			mig.Namespace = namespace
 			migs = append(migs, mig)
 		}
 
@@ -168,26 +548,113 @@ func (d DefaultDatabase) History(ctx context.Context) ([]Migration, error) {
 	return migs, err
 }
 
-func (d DefaultDatabase) Migrate(ctx context.Context, name, content string) error {
-	start := d.clock.Now()
+func (d DefaultDatabase) Rename(ctx context.Context, namespace string, names map[string]string) error {
+	olds := make([]string, 0, len(names))
+
+	for old := range names {
+		olds = append(olds, old)
+	}
+
+	sort.Strings(olds)
+
+	return d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		for _, old := range olds {
+			mig := Migration{Name: old, Namespace: namespace}
+
+			if err := repo.Exec(ctx, d.stmts.RenameMigration(mig, names[old])); err != nil {
+				return newError(err, "failed to rename migration %s to %s", old, names[old])
+			}
+		}
+
+		return nil
+	})
+}
+
+func (d DefaultDatabase) scanHistory(rows *sql.Rows) (Migration, error) {
+	var (
+		mig                                    Migration
+		status                                 string
+		errMsg, desc, hostname, osUser, dbUser sql.NullString
+		version                                sql.NullString
+		pid, progress                          sql.NullInt64
+	)
+
+	if err := rows.Scan(
+		&mig.Name,
+		&mig.Start,
+		&mig.DurationMs,
+		&mig.Checksum,
+		&status,
+		&errMsg,
+		&mig.InstalledRank,
+		&desc,
+		&hostname,
+		&pid,
+		&osUser,
+		&dbUser,
+		&version,
+		&progress,
+	); err != nil {
+		return mig, newError(err, "failed to scan database history")
+	}
+
+	mig.Status = MigrationStatus(status)
+	mig.Error = errMsg.String
+	mig.Description = desc.String
+	mig.Hostname = hostname.String
+	mig.Pid = int(pid.Int64)
+	mig.OsUser = osUser.String
+	mig.DBUser = dbUser.String
+	mig.Version = version.String
+	mig.Progress = int(progress.Int64)
+
+	return mig, nil
+}
+
+func (d DefaultDatabase) Migrate(ctx context.Context, mig Migration, content string) error {
+	mig.Start = d.clock.Now()
+	mig.Checksum = checksum(content)
+	mig.DBUser = d.currentUser(ctx)
+
+	if !d.repo.Transactional() {
+		return d.migrateStatements(ctx, mig, content)
+	}
+
+	stmts := d.stmts.Script(content)
 
 	if err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
-		return repo.Exec(ctx, NewStatement("%s", content))
+		for _, stmt := range stmts {
+			if err := repo.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		return nil
 	}); err != nil {
-		return newError(err, "migration %s failed", name)
+		mig.DurationMs = d.clock.Now().Sub(mig.Start).Milliseconds()
+		mig.Status = MigrationFailed
+		mig.Error = err.Error()
+
+		return d.fail(ctx, mig, err)
 	}
 
-	duration := d.clock.Now().Sub(start)
+	mig.DurationMs = d.clock.Now().Sub(mig.Start).Milliseconds()
+	mig.Progress = len(stmts)
+	mig.Status = MigrationSucceeded
 
 	return d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
-		mig := Migration{
-			Name:       name,
-			Start:      start,
-			DurationMs: duration.Milliseconds(),
-			Checksum:   checksum(content),
+		if err := repo.Exec(ctx, d.stmts.DeleteFailure(mig)); err != nil {
+			return newError(err, "failed to delete previous failure of migration %s", mig.Name)
 		}
 
-		if err := repo.Exec(ctx, d.stmts.Log(mig)); err != nil {
+		rank, err := d.nextInstalledRank(ctx, repo)
+		if err != nil {
+			return err
+		}
+
+		mig.InstalledRank = rank
+
+		if err = repo.Exec(ctx, d.stmts.Log(mig)); err != nil {
 			return newError(err, "failed to save migration %s", mig.Name)
 		}
 
@@ -195,24 +662,191 @@ func (d DefaultDatabase) Migrate(ctx context.Context, name, content string) erro
 	})
 }
 
+func (d DefaultDatabase) migrateStatements(ctx context.Context, mig Migration, content string) error {
+	stmts := d.stmts.SplitScript(content)
+	cks := mig.Checksum
+
+	if err := d.startProgress(ctx, &mig, stmts); err != nil {
+		return err
+	}
+
+	for ; mig.Progress < len(stmts); mig.Progress++ {
+		if err := d.repo.Exec(ctx, stmts[mig.Progress]); err != nil {
+			mig.Error = err.Error()
+
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+			defer cancel()
+
+			return d.saveProgress(
+				cleanupCtx,
+				mig,
+				newError(err, "migration %s failed at statement %d of %d", mig.Name, mig.Progress+1, len(stmts)),
+			)
+		}
+
+		mig.Checksum = progressChecksum(stmts, mig.Progress+1)
+		mig.Error = progressMessage(mig.Progress+1, len(stmts))
+
+		if err := d.saveProgress(ctx, mig, nil); err != nil {
+			return err
+		}
+	}
+
+	mig.Checksum = cks
+	mig.Status = MigrationSucceeded
+	mig.Error = ""
+
+	return d.saveProgress(ctx, mig, nil)
+}
+
+func (d DefaultDatabase) startProgress(ctx context.Context, mig *Migration, stmts []*Statement) error {
+	count := len(stmts)
+
+	history, err := d.History(ctx, mig.Namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, hist := range history {
+		if hist.Name != mig.Name {
+			continue
+		}
+
+		if hist.Progress > count {
+			return newError(nil,
+				"migration %s has %d statement(s) but %d were already applied",
+				mig.Name,
+				count,
+				hist.Progress,
+			)
+		}
+
+		if hist.Checksum != progressChecksum(stmts, hist.Progress) {
+			return newError(nil,
+				"migration %s changed in its %d statement(s) already applied, refusing to resume",
+				mig.Name,
+				hist.Progress,
+			)
+		}
+
+		d.logger.Log(fmt.Sprintf("Resuming migration %s after %d statement(s)...", mig.Name, hist.Progress))
+
+		mig.Checksum = hist.Checksum
+		mig.InstalledRank = hist.InstalledRank
+		mig.Progress = hist.Progress
+		mig.Status = MigrationFailed
+
+		return nil
+	}
+
+	rank, err := d.nextInstalledRank(ctx, d.repo)
+	if err != nil {
+		return err
+	}
+
+	mig.InstalledRank = rank
+	mig.Checksum = progressChecksum(stmts, 0)
+	mig.Status = MigrationFailed
+	mig.Error = progressMessage(0, count)
+
+	if err = d.repo.Exec(ctx, d.stmts.LogFailure(*mig)); err != nil {
+		return newError(err, "failed to save migration %s", mig.Name)
+	}
+
+	return nil
+}
+
+func (d DefaultDatabase) saveProgress(ctx context.Context, mig Migration, cause error) error {
+	mig.DurationMs = d.clock.Now().Sub(mig.Start).Milliseconds()
+
+	if err := d.repo.Exec(ctx, d.stmts.UpdateProgress(mig)); err != nil {
+		return errors.Join(cause, newError(err, "failed to save progress of migration %s", mig.Name))
+	}
+
+	return cause
+}
+
+func (d DefaultDatabase) fail(ctx context.Context, mig Migration, cause error) error {
+	result := newError(cause, "migration %s failed", mig.Name)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+	defer cancel()
+
+	if err := d.repo.EnsureTransaction(ctx, nil, func(ctx context.Context, repo Repository) error {
+		if err := repo.Exec(ctx, d.stmts.DeleteFailure(mig)); err != nil {
+			return err
+		}
+
+		rank, err := d.nextInstalledRank(ctx, repo)
+		if err != nil {
+			return err
+		}
+
+		mig.InstalledRank = rank
+
+		return repo.Exec(ctx, d.stmts.LogFailure(mig))
+	}); err != nil {
+		d.logger.Log(fmt.Sprintf("failed to save failure of migration %s: %v", mig.Name, err))
+	}
+
+	return result
+}
+
+func (d DefaultDatabase) nextInstalledRank(ctx context.Context, repo Repository) (int, error) {
+	var result int
+
+	if err := repo.QueryRow(ctx, d.stmts.MaxInstalledRank()).Scan(&result); err != nil {
+		return 0, newError(err, "failed to find installed rank")
+	}
+
+	return result + 1, nil
+}
+
+func (d DefaultDatabase) currentUser(ctx context.Context) string {
+	var result sql.NullString
+
+	err := d.repo.EnsureTransaction(ctx, d.ReadOnlyTx(), func(ctx context.Context, repo Repository) error {
+		return repo.QueryRow(ctx, d.stmts.CurrentUser()).Scan(&result)
+	})
+	if err != nil {
+		d.logger.Log(fmt.Sprintf("Failed to find database user: %v", err))
+	}
+
+	return result.String
+}
+
 func (d DefaultDatabase) Unlock(ctx context.Context, lck Lock) error {
 	d.logger.Log("Freeing lock...")
 
+	var count int64
+
 	err := d.repo.EnsureTransaction(ctx, nil,
 		func(ctx context.Context, repo Repository) error {
-			return repo.Exec(ctx, d.stmts.Unlock(lck))
+			var err error
+
+			count, err = repo.ExecAffected(ctx, d.stmts.Unlock(lck))
+
+			return err
 		},
 	)
 	if err != nil {
 		return newError(err, "failed to free lock")
 	}
 
+	if count == 0 {
+		return newError(ErrLockLost, "failed to free lock held since %v", lck.since)
+	}
+
 	d.logger.Log("Lock successfully freed")
 
 	return nil
 }
 
 func (d DefaultDatabase) ReadOnlyTx() *sql.TxOptions {
+	if !d.stmts.ReadOnlyTransactions() {
+		return nil
+	}
+
 	return &sql.TxOptions{ReadOnly: true}
 }
 
@@ -225,3 +859,17 @@ func (d DefaultDatabase) String() string {
 		d.stmts,
 	)
 }
+
+func progressChecksum(stmts []*Statement, applied int) string {
+	sqls := make([]string, applied)
+
+	for i, stmt := range stmts[:applied] {
+		sqls[i] = stmt.sql
+	}
+
+	return checksum(strings.Join(sqls, "\n;\n"))
+}
+
+func progressMessage(applied, count int) string {
+	return fmt.Sprintf("applied %d of %d statement(s)", applied, count)
+}
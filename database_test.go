@@ -0,0 +1,407 @@
+package dejavu
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	legacyCreateHistoryTable = `create table deja_vu_history (
+		name        varchar(512) not null,
+		started_at  timestamp    not null,
+		duration_ms int          not null,
+		checksum    char(43)     not null,
+		constraint deja_vu_history_pk primary key (name)
+	)`
+	legacyCreateLockTable = `create table deja_vu_lock (
+		id       int          not null,
+		hostname varchar(128) not null,
+		pid      int          not null,
+		since    timestamp    not null,
+		constraint deja_vu_lock_pk primary key (id)
+	)`
+)
+
+func TestDefaultDatabase_Init(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	version, err := database.MetadataVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, -1, version)
+
+	require.NoError(t, database.Init(ctx))
+
+	version, err = database.MetadataVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MetadataVersion, version)
+
+	require.NoError(t, database.Init(ctx))
+}
+
+func TestDefaultDatabase_ReadOnlyTx(t *testing.T) {
+	logger := newTestLogger(t)
+	repo := NewRepository(nil, logger, PlaceholdersQuestionMark())
+
+	assert.Equal(t,
+		&sql.TxOptions{ReadOnly: true},
+		NewDatabase(newTestClock(), logger, "sqlite", repo, NewSQLiteStatements(DefaultStatements{})).ReadOnlyTx(),
+	)
+	assert.Nil(t,
+		NewDatabase(newTestClock(), logger, "sqlserver", repo, NewSQLServerStatements(DefaultStatements{})).ReadOnlyTx(),
+	)
+}
+
+func TestDefaultDatabase_Init_Legacy(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, legacyCreateHistoryTable)
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, legacyCreateLockTable)
+	require.NoError(t, err)
+
+	migs, err := dv.migs.List("sqlite")
+	require.NoError(t, err)
+
+	for _, mig := range migs[:2] {
+		content, err := dv.migs.Content(mig)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, content)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx,
+			"insert into deja_vu_history (name, started_at, duration_ms, checksum) values (?, ?, ?, ?)",
+			mig,
+			now,
+			42,
+			checksum(content),
+		)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	version, err := database.MetadataVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MetadataVersion, version)
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	for i, hist := range history {
+		assert.Equal(t, migs[i], hist.Name)
+		assert.Equal(t, i+1, hist.InstalledRank)
+		assert.Equal(t, MigrationSucceeded, hist.Status)
+		assert.Equal(t, Describe(migs[i]), hist.Description)
+	}
+
+	assert.Empty(t, history[0].Hostname)
+	assert.NotEmpty(t, history[2].Hostname)
+}
+
+func TestDefaultDatabase_Init_ResumeHistoryRebuild(t *testing.T) {
+	for crash := 1; crash < 5; crash++ {
+		t.Run(fmt.Sprintf("after statement %d", crash), func(t *testing.T) {
+			db, syntax := sqlite(t)
+			dv := newTestConfig(t, db, "sqlite", syntax).Build()
+			ctx := context.Background()
+
+			database, ok := dv.db.(DefaultDatabase)
+			require.True(t, ok)
+
+			_, err := db.ExecContext(ctx, legacyCreateHistoryTable)
+			require.NoError(t, err)
+
+			for _, name := range []string{"01_a.sql", "02_b.sql"} {
+				_, err = db.ExecContext(ctx,
+					"insert into deja_vu_history (name, started_at, duration_ms, checksum) values (?, ?, ?, ?)",
+					name,
+					now,
+					42,
+					checksum(name),
+				)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, database.InitMetadataTable(ctx, 0))
+			require.NoError(t, database.UpgradeMetadata(ctx, 0))
+
+			for _, stmt := range database.stmts.UpgradeMetadata(1)[:crash] {
+				_, err = db.ExecContext(ctx, stmt.sql)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, database.Init(ctx))
+
+			version, err := database.MetadataVersion(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, MetadataVersion, version)
+
+			exist, err := database.Exist(ctx, database.stmts.HistoryBackupTable())
+			require.NoError(t, err)
+			assert.False(t, exist)
+
+			history, err := dv.History(ctx)
+			require.NoError(t, err)
+			require.Len(t, history, 2)
+			assert.Equal(t, "01_a.sql", history[0].Name)
+			assert.Equal(t, DefaultNamespace, history[1].Namespace)
+		})
+	}
+}
+
+func TestDefaultDatabase_Init_MissingMetadata(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	require.NoError(t, database.InitHistoryTable(ctx))
+	require.NoError(t, database.Init(ctx))
+
+	version, err := database.MetadataVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MetadataVersion, version)
+}
+
+func TestDefaultDatabase_Init_MissingHistory(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	require.NoError(t, database.InitMetadataTable(ctx, MetadataVersion))
+	require.NoError(t, database.Init(ctx))
+
+	exist, err := database.Exist(ctx, database.stmts.HistoryTable())
+	require.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestDefaultDatabase_Init_ResumeAddColumns(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	_, err := db.ExecContext(ctx, legacyCreateHistoryTable)
+	require.NoError(t, err)
+
+	require.NoError(t, database.InitMetadataTable(ctx, 0))
+
+	for _, stmt := range database.stmts.UpgradeMetadata(0)[:4] {
+		_, err = db.ExecContext(ctx, stmt.sql)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, database.Init(ctx))
+
+	version, err := database.MetadataVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MetadataVersion, version)
+	assert.True(t, database.HasColumn(ctx, database.stmts.HistoryTable(), HistoryColumnVersion))
+}
+
+func TestDefaultDatabase_Init_Newer(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	require.NoError(t, database.Init(ctx))
+
+	_, err := db.ExecContext(ctx, "update deja_vu_metadata set version = ?", MetadataVersion+1)
+	require.NoError(t, err)
+
+	assert.Error(t, database.Init(ctx))
+}
+
+func TestDefaultDatabase_Refresh(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	require.NoError(t, database.InitLock(ctx))
+
+	lck, err := NewLock(DefaultNamespace)
+	require.NoError(t, err)
+	require.True(t, database.Lock(ctx, lck))
+
+	_, err = db.ExecContext(ctx, "update deja_vu_lock set since = ?", "2020-01-01 00:00:00")
+	require.NoError(t, err)
+
+	require.NoError(t, database.Refresh(ctx, lck))
+
+	holder, expired, err := database.Expire(ctx, lck, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, expired)
+	assert.Equal(t, lck.hostname, holder.hostname)
+	assert.Greater(t, holder.since.Year(), 2020)
+
+	require.NoError(t, database.Unlock(ctx, lck))
+}
+
+func TestDefaultDatabase_Unlock_Lost(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	require.NoError(t, database.InitLock(ctx))
+
+	lck, err := NewLock(DefaultNamespace)
+	require.NoError(t, err)
+	require.True(t, database.Lock(ctx, lck))
+
+	other, err := NewLock(DefaultNamespace)
+	require.NoError(t, err)
+	require.NotEqual(t, lck.token, other.token)
+	require.False(t, database.Lock(ctx, other))
+
+	require.ErrorIs(t, database.Refresh(ctx, other), ErrLockLost)
+	require.ErrorIs(t, database.Unlock(ctx, other), ErrLockLost)
+
+	count, err := database.Count(ctx, "deja_vu_lock")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, database.Unlock(ctx, lck))
+	require.ErrorIs(t, database.Unlock(ctx, lck), ErrLockLost)
+}
+
+func TestDefaultDatabase_InitLock_Legacy(t *testing.T) {
+	db, syntax := sqlite(t)
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	ctx := context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	_, err := db.ExecContext(ctx, legacyCreateLockTable)
+	require.NoError(t, err)
+	assert.False(t, database.HasColumn(ctx, "deja_vu_lock", LockColumnToken))
+
+	require.NoError(t, database.InitLock(ctx))
+	assert.True(t, database.HasColumn(ctx, "deja_vu_lock", LockColumnToken))
+
+	require.NoError(t, database.InitLock(ctx))
+}
+
+func TestDefaultDatabase_Migrate_WithoutTransactions(t *testing.T) {
+	db, syntax := sqlite(t)
+	clock := newTestClock()
+	logger := newTestLogger(t)
+	repo := NewRepository(db, logger, syntax).WithoutTransactions()
+	database := NewDatabase(clock, logger, "sqlite", repo, NewSQLiteStatements(DefaultStatements{}))
+	fsys := fstest.MapFS{
+		"01_init.sql": {Data: []byte(
+			"create table country (name text not null);\n" +
+				"insert into unknown_table values ('France');\n" +
+				"insert into country values ('Italy');\n",
+		)},
+	}
+	dv := NewConfig(database, FsMigrations{fs: fsys}).WithClock(clock).WithLogger(logger).Build()
+	ctx := context.Background()
+
+	assert.False(t, repo.Transactional())
+	require.Error(t, dv.Upgrade(ctx))
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, MigrationFailed, history[0].Status)
+	assert.Equal(t, 1, history[0].Progress)
+	assert.Contains(t, history[0].Error, "unknown_table")
+
+	fsys["01_init.sql"] = &fstest.MapFile{Data: []byte(
+		"create table country (name text not null);\n" +
+			"insert into country values ('France');\n" +
+			"insert into country values ('Italy');\n",
+	)}
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	history, err = dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, MigrationSucceeded, history[0].Status)
+	assert.Equal(t, 3, history[0].Progress)
+	assert.Empty(t, history[0].Error)
+	assert.Equal(t, 1, history[0].InstalledRank)
+	assert.Equal(t, checksum(string(fsys["01_init.sql"].Data)), history[0].Checksum)
+
+	count, err := database.Count(ctx, "country")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestDefaultDatabase_Migrate_WithoutTransactions_Changed(t *testing.T) {
+	db, syntax := sqlite(t)
+	clock := newTestClock()
+	logger := newTestLogger(t)
+	repo := NewRepository(db, logger, syntax).WithoutTransactions()
+	database := NewDatabase(clock, logger, "sqlite", repo, NewSQLiteStatements(DefaultStatements{}))
+	fsys := fstest.MapFS{
+		"01_init.sql": {Data: []byte(
+			"create table country (name text not null);\n" +
+				"insert into country values ('France');\n" +
+				"insert into unknown_table values ('Italy');\n",
+		)},
+	}
+	dv := NewConfig(database, FsMigrations{fs: fsys}).WithClock(clock).WithLogger(logger).Build()
+	ctx := context.Background()
+
+	require.Error(t, dv.Upgrade(ctx))
+
+	fsys["01_init.sql"] = &fstest.MapFile{Data: []byte(
+		"create table country (name text not null);\n" +
+			"create index country_name on country (name);\n" +
+			"insert into country values ('France');\n" +
+			"insert into country values ('Italy');\n",
+	)}
+
+	err := dv.Upgrade(ctx)
+	require.ErrorContains(t, err, "migration 01_init.sql changed in its 2 statement(s) already applied, refusing to resume")
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, MigrationFailed, history[0].Status)
+	assert.Equal(t, 2, history[0].Progress)
+
+	count, err := database.Count(ctx, "country")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -5,14 +5,17 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"os"
 	"os/signal"
-	"syscall"
+	"os/user"
+	"strings"
 	"text/template"
 	"time"
 )
 
+var ErrLockLost = errors.New("lock lost")
+
 type Error struct {
 	Cause   error
 	Message string
@@ -22,6 +25,10 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %v", e.Message, e.Cause)
 }
 
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
 func newError(cause error, format string, args ...any) error {
 	return &Error{
 		Cause:   cause,
@@ -34,11 +41,52 @@ type DejaVu struct {
 }
 
 func (dv DejaVu) History(ctx context.Context) ([]Migration, error) {
-	return dv.db.History(ctx)
+	return dv.db.History(ctx, dv.ns)
+}
+
+func (dv DejaVu) Status(ctx context.Context) ([]Migration, error) {
+	history, err := dv.db.History(ctx, dv.ns)
+	if err != nil {
+		return nil, err
+	}
+
+	migs, err := dv.migs.List(dv.db.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	histByID := make(map[string]Migration, len(history))
+
+	for _, hist := range history {
+		histByID[dv.historyID(hist.Name)] = hist
+	}
+
+	result := make([]Migration, 0, len(migs))
+
+	for _, mig := range migs {
+		id := dv.migrationID(mig)
+
+		hist, found := histByID[id]
+		if found {
+			delete(histByID, id)
+		} else {
+			hist = Migration{Name: mig, Status: MigrationPending}
+		}
+
+		result = append(result, hist)
+	}
+
+	for _, hist := range history {
+		if _, found := histByID[dv.historyID(hist.Name)]; found {
+			result = append(result, hist)
+		}
+	}
+
+	return result, nil
 }
 
 func (dv DejaVu) Missing(ctx context.Context) ([]string, error) {
-	history, err := dv.db.History(ctx)
+	history, err := dv.succeeded(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -55,11 +103,11 @@ func (dv DejaVu) Missing(ctx context.Context) ([]string, error) {
 		hist := history[histIdx]
 		mig := migs[migIdx]
 
-		if hist.Name != mig {
+		if dv.historyID(hist.Name) != dv.migrationID(mig) {
 			return nil, newError(nil, "mismatch between history %s and migration %s", hist.Name, mig)
 		}
 
-		content, err := dv.migs.Content(mig)
+		content, err := dv.render(mig)
 		if err != nil {
 			return nil, err
 		}
@@ -87,20 +135,219 @@ func (dv DejaVu) Missing(ctx context.Context) ([]string, error) {
 	return migs[migIdx:], nil
 }
 
+func (dv DejaVu) rename(ctx context.Context) error {
+	history, err := dv.db.History(ctx, dv.ns)
+	if err != nil {
+		return err
+	}
+
+	migs, err := dv.migs.List(dv.db.Name())
+	if err != nil {
+		return err
+	}
+
+	migByID := make(map[string]string, len(migs))
+
+	for _, mig := range migs {
+		migByID[dv.migrationID(mig)] = mig
+	}
+
+	histNames := make(map[string]bool, len(history))
+
+	for _, hist := range history {
+		histNames[hist.Name] = true
+	}
+
+	names := make(map[string]string)
+
+	for _, hist := range history {
+		mig, found := migByID[dv.historyID(hist.Name)]
+		if !found || mig == hist.Name {
+			continue
+		}
+
+		if histNames[mig] {
+			return newError(nil, "failed to rename migration %s to %s: already in history", hist.Name, mig)
+		}
+
+		dv.logger.Log(fmt.Sprintf("Renaming migration %s to %s", hist.Name, mig))
+
+		histNames[mig] = true
+		names[hist.Name] = mig
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	return dv.db.Rename(ctx, dv.ns, names)
+}
+
+func (dv DejaVu) historyID(name string) string {
+	if alias, found := dv.aliases[name]; found {
+		name = alias
+	}
+
+	return dv.migrationID(name)
+}
+
+func (dv DejaVu) migrationID(name string) string {
+	if migs, ok := dv.migs.(VersionedMigrations); ok {
+		if version, err := migs.Version(name); err == nil {
+			return version.ID
+		}
+	}
+
+	return name
+}
+
+func (dv DejaVu) describe(name string) string {
+	if migs, ok := dv.migs.(VersionedMigrations); ok {
+		if version, err := migs.Version(name); err == nil {
+			return DescribeVersion(name, version)
+		}
+	}
+
+	return Describe(name)
+}
+
+func (dv DejaVu) succeeded(ctx context.Context) ([]Migration, error) {
+	history, err := dv.db.History(ctx, dv.ns)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Migration, 0, len(history))
+
+	for _, hist := range history {
+		if hist.Failed() {
+			dv.logger.Log(fmt.Sprintf("Migration %s previously failed on %v: %s",
+				hist.Name,
+				hist.Start,
+				hist.Error,
+			))
+
+			continue
+		}
+
+		result = append(result, hist)
+	}
+
+	return result, nil
+}
+
+func (dv DejaVu) LockStatus(ctx context.Context) (Lock, bool, error) {
+	lck, err := dv.tableLock()
+	if err != nil {
+		return lck, false, err
+	}
+
+	return dv.db.LockStatus(ctx, lck)
+}
+
+func (dv DejaVu) ForceUnlock(ctx context.Context, force bool) (Lock, bool, error) {
+	lck, err := dv.tableLock()
+	if err != nil {
+		return lck, false, err
+	}
+
+	if !force {
+		return lck, false, newError(nil, "refusing to force unlock without explicit force flag")
+	}
+
+	holder, found, err := dv.db.ForceUnlock(ctx, lck)
+	if err != nil {
+		return holder, false, err
+	}
+
+	if found {
+		dv.logger.Log(fmt.Sprintf("Evicted lock from host %s by PID %d since %v",
+			holder.hostname,
+			holder.pid,
+			holder.since,
+		))
+	} else {
+		dv.logger.Log("No lock to evict")
+	}
+
+	return holder, found, nil
+}
+
+func (dv DejaVu) tableLock() (Lock, error) {
+	if _, ok := dv.locker.(Database); !ok {
+		return Lock{}, newError(nil, "lock inspection is not supported by %v", dv.locker)
+	}
+
+	return NewLock(dv.ns)
+}
+
+func (dv DejaVu) Init(ctx context.Context) error {
+	return dv.withLock(ctx, func(context.Context, Lock) error {
+		return nil
+	})
+}
+
 func (dv DejaVu) Upgrade(ctx context.Context) error {
 	dv.logger.Log("Starting database upgrade...")
 
-	if err := dv.db.Init(ctx); err != nil {
+	if len(dv.signals) > 0 {
+		var stop context.CancelFunc
+
+		ctx, stop = signal.NotifyContext(ctx, dv.signals...)
+		defer stop()
+	}
+
+	if err := dv.withLock(ctx, dv.doUpgrade); err != nil {
 		return err
 	}
 
-	lck, err := dv.lock(ctx)
+	dv.logger.Log("Database successfully upgraded")
+
+	return nil
+}
+
+func (dv DejaVu) withLock(ctx context.Context, f func(ctx context.Context, lck Lock) error) error {
+	if err := dv.locker.InitLock(ctx); err != nil {
+		return err
+	}
+
+	if dv.ns == DefaultNamespace {
+		return dv.withNamespaceLock(ctx, dv.ns, func(ctx context.Context, lck Lock) error {
+			if err := dv.db.Init(ctx); err != nil {
+				return err
+			}
+
+			return f(ctx, lck)
+		})
+	}
+
+	if err := dv.withNamespaceLock(ctx, DefaultNamespace, func(ctx context.Context, _ Lock) error {
+		return dv.db.Init(ctx)
+	}); err != nil {
+		return err
+	}
+
+	return dv.withNamespaceLock(ctx, dv.ns, f)
+}
+
+func (dv DejaVu) withNamespaceLock(
+	ctx context.Context,
+	namespace string,
+	f func(ctx context.Context, lck Lock) error,
+) (err error) {
+	lck, err := dv.lock(ctx, namespace)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
 	defer func() {
-		if err2 := dv.db.Unlock(ctx, lck); err2 != nil {
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+		defer cancel()
+
+		if err2 := dv.locker.Unlock(unlockCtx, lck); err2 != nil {
 			dv.logger.Log(fmt.Sprintf("failed to free lock: %v", err2))
 
 			if err == nil {
@@ -109,87 +356,263 @@ func (dv DejaVu) Upgrade(ctx context.Context) error {
 		}
 	}()
 
-	if err = dv.doUpgrade(ctx); err != nil {
-		return err
-	}
+	defer dv.startHeartbeat(ctx, lck, cancel)()
 
-	dv.logger.Log("Database successfully upgraded")
+	if err = f(ctx, lck); err != nil && !errors.Is(err, ErrLockLost) && errors.Is(context.Cause(ctx), ErrLockLost) {
+		return context.Cause(ctx)
+	}
 
 	return err
 }
 
-func (dv DejaVu) doUpgrade(ctx context.Context) error {
+func (dv DejaVu) doUpgrade(ctx context.Context, lck Lock) error {
+	if err := dv.rename(ctx); err != nil {
+		return err
+	}
+
 	migs, err := dv.Missing(ctx)
 	if err != nil {
 		return err
 	}
 
-	for _, mig := range migs {
-		dv.logger.Log(fmt.Sprintf("Processing migration %v...", mig))
+	completed := make([]string, 0, len(migs))
 
-		content, err := dv.migs.Content(mig)
-		if err != nil {
-			return err
+	for _, mig := range migs {
+		if ctx.Err() != nil {
+			return interrupted(context.Cause(ctx), completed)
 		}
 
-		tmpl, err := template.New(mig).Parse(content)
-		if err != nil {
-			return newError(err, "failed to parse template %s", mig)
-		}
+		if err = dv.migrate(ctx, mig, lck); err != nil {
+			if ctx.Err() != nil {
+				err = context.Cause(ctx)
+			}
 
-		var buf bytes.Buffer
-		if err = tmpl.Execute(&buf, nil); err != nil {
-			return newError(err, "failed to execute template %s", mig)
+			return interrupted(err, completed)
 		}
 
-		if err = dv.db.Migrate(ctx, mig, buf.String()); err != nil {
-			return err
-		}
+		completed = append(completed, mig)
+	}
 
-		dv.logger.Log(fmt.Sprintf("Migration %v successfully processed", mig))
+	return nil
+}
+
+func (dv DejaVu) migrate(ctx context.Context, mig string, lck Lock) error {
+	dv.logger.Log(fmt.Sprintf("Processing migration %v...", mig))
+
+	content, err := dv.render(mig)
+	if err != nil {
+		return err
 	}
 
+	if err = dv.db.Migrate(ctx, dv.newMigration(mig, lck), content); err != nil {
+		return err
+	}
+
+	dv.logger.Log(fmt.Sprintf("Migration %v successfully processed", mig))
+
 	return nil
 }
 
-func (dv DejaVu) lock(ctx context.Context) (Lock, error) {
+func (dv DejaVu) render(mig string) (string, error) {
+	content, err := dv.migs.Content(mig)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(mig).Parse(content)
+	if err != nil {
+		return "", newError(err, "failed to parse template %s", mig)
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, dv.data); err != nil {
+		return "", newError(err, "failed to execute template %s", mig)
+	}
+
+	return buf.String(), nil
+}
+
+func (dv DejaVu) newMigration(name string, lck Lock) Migration {
+	return Migration{
+		Name:        name,
+		Namespace:   dv.ns,
+		Description: dv.describe(name),
+		Hostname:    lck.hostname,
+		Pid:         lck.pid,
+		OsUser:      osUser(),
+		Version:     dv.version,
+	}
+}
+
+func (dv DejaVu) lock(ctx context.Context, namespace string) (Lock, error) {
 	start := dv.clock.Now()
 
-	lck, err := NewLock()
+	lck, err := NewLock(namespace)
 	if err != nil {
 		return lck, err
 	}
 
 	lck.since = start
 
-	if dv.db.Lock(ctx, lck) {
+	if dv.tryLock(ctx, lck) {
 		return lck, nil
 	}
 
-	ticker := time.NewTicker(dv.tick)
-	defer ticker.Stop()
+	var holder *Lock
+
+	for attempt := 0; ; attempt++ {
+		holder = dv.lockHolder(ctx, lck, holder)
+		wait := dv.backoff.Next(attempt)
+
+		if holder != nil {
+			dv.logger.Log(fmt.Sprintf("Lock held by host %s with PID %d for %v, retrying in %v...",
+				holder.hostname,
+				holder.pid,
+				holder.age,
+				wait,
+			))
+		} else {
+			dv.logger.Log(fmt.Sprintf("Lock not available, retrying in %v...", wait))
+		}
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		timer := time.NewTimer(wait)
 
-	for {
 		select {
 		case <-ctx.Done():
+			timer.Stop()
+
 			return lck, newError(ctx.Err(), "canceling lock acquisition")
-		case sig := <-sigs:
-			return lck, newError(nil, "aborting lock acquisition because of %v", sig)
-		case <-ticker.C:
-			lck.since = dv.clock.Now()
+		case <-timer.C:
+		}
+
+		lck.since = dv.clock.Now()
 
-			if lck.since.Sub(start) > dv.timeout {
-				return lck, newError(nil, "failed to acquired lock after %v", dv.timeout)
+		if lck.since.Sub(start) > dv.timeout {
+			if holder != nil {
+				return lck, newError(nil, "failed to acquire lock after %v, last held by host %s with PID %d since %v",
+					dv.timeout,
+					holder.hostname,
+					holder.pid,
+					holder.since,
+				)
 			}
 
-			if dv.db.Lock(ctx, lck) {
-				return lck, nil
+			return lck, newError(nil, "failed to acquire lock after %v", dv.timeout)
+		}
+
+		if dv.tryLock(ctx, lck) {
+			return lck, nil
+		}
+	}
+}
+
+func (dv DejaVu) lockHolder(ctx context.Context, lck Lock, last *Lock) *Lock {
+	db, ok := dv.locker.(Database)
+	if !ok {
+		return last
+	}
+
+	holder, found, err := db.LockStatus(ctx, lck)
+	if err != nil {
+		dv.logger.Log(fmt.Sprintf("failed to read lock holder: %v", err))
+
+		return last
+	}
+
+	if !found {
+		return last
+	}
+
+	return &holder
+}
+
+func (dv DejaVu) tryLock(ctx context.Context, lck Lock) bool {
+	if dv.locker.Lock(ctx, lck) {
+		return true
+	}
+
+	if dv.ttl <= 0 {
+		return false
+	}
+
+	holder, expired, err := dv.locker.Expire(ctx, lck, dv.ttl)
+	if err != nil {
+		dv.logger.Log(fmt.Sprintf("failed to expire lock: %v", err))
+
+		return false
+	}
+
+	if !expired {
+		return false
+	}
+
+	dv.logger.Log(fmt.Sprintf("Taking over stale lock from host %s by PID %d since %v",
+		holder.hostname,
+		holder.pid,
+		holder.since,
+	))
+
+	return dv.locker.Lock(ctx, lck)
+}
+
+func (dv DejaVu) startHeartbeat(ctx context.Context, lck Lock, cancel context.CancelCauseFunc) func() {
+	if dv.heartbeat <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(dv.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := dv.locker.Refresh(ctx, lck); err != nil {
+					dv.logger.Log(fmt.Sprintf("failed to refresh lock: %v", err))
+
+					if errors.Is(err, ErrLockLost) {
+						cancel(err)
+
+						return
+					}
+				}
 			}
 		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func interrupted(cause error, completed []string) error {
+	if len(completed) == 0 {
+		return newError(cause, "upgrade stopped before completing any migration")
 	}
+
+	return newError(cause, "upgrade stopped after completing %d migration(s) %s",
+		len(completed),
+		strings.Join(completed, ", "),
+	)
+}
+
+func osUser() string {
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return usr.Username
 }
 
 func checksum(s string) string {
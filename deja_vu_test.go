@@ -3,8 +3,11 @@ package dejavu
 import (
 	"context"
 	"database/sql"
+	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -78,17 +81,36 @@ func TestDejaVu_Upgrade(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			db, syntax := tt.setup(t)
-			dv := newTestConfig(t, db, tt.name, syntax).Build()
+			dv := newTestConfig(t, db, tt.name, syntax).WithVersion("1.2.3").Build()
 			ctx := context.Background()
 
 			err := dv.Upgrade(ctx)
 			require.NoError(t, err)
 
+			history, err := dv.History(ctx)
+			require.NoError(t, err)
+			require.Len(t, history, 3)
+
+			hostname, err := os.Hostname()
+			require.NoError(t, err)
+
+			for i, hist := range history {
+				assert.Equal(t, i+1, hist.InstalledRank)
+				assert.Equal(t, hostname, hist.Hostname)
+				assert.Equal(t, os.Getpid(), hist.Pid)
+				assert.Equal(t, "1.2.3", hist.Version)
+			}
+
+			assert.Equal(t, "create country index", history[1].Description)
+
 			database, ok := dv.db.(DefaultDatabase)
 			require.True(t, ok)
 
-			assert.True(t, database.Exist(ctx, "deja_vu_history"))
-			assert.True(t, database.Exist(ctx, "deja_vu_lock"))
+			for _, table := range []string{"deja_vu_history", "deja_vu_lock", "deja_vu_metadata"} {
+				exist, err := database.Exist(ctx, table)
+				require.NoError(t, err)
+				assert.True(t, exist, table)
+			}
 
 			count, err := database.Count(ctx, "deja_vu_history")
 			require.NoError(t, err)
@@ -104,3 +126,470 @@ func TestDejaVu_Upgrade(t *testing.T) {
 		})
 	}
 }
+
+func TestDejaVu_Upgrade_Failure(t *testing.T) {
+	db, syntax := sqlite(t)
+	fsys := fstest.MapFS{
+		"01_create_table.sql": {Data: []byte("create table test_table (id int not null);")},
+		"02_insert.sql":       {Data: []byte("insert into unknown_table values (1);")},
+		"03_insert.sql":       {Data: []byte("insert into test_table values (2);")},
+	}
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	dv.migs = FsMigrations{fs: fsys}
+	ctx := context.Background()
+
+	err := dv.Upgrade(ctx)
+	require.Error(t, err)
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, MigrationSucceeded, history[0].Status)
+	assert.Empty(t, history[0].Error)
+	assert.Equal(t, "02_insert.sql", history[1].Name)
+	assert.Equal(t, MigrationFailed, history[1].Status)
+	assert.Contains(t, history[1].Error, "unknown_table")
+
+	status, err := dv.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.Equal(t, MigrationSucceeded, status[0].Status)
+	assert.Equal(t, MigrationFailed, status[1].Status)
+	assert.Equal(t, "03_insert.sql", status[2].Name)
+	assert.Equal(t, MigrationPending, status[2].Status)
+
+	fsys["02_insert.sql"] = &fstest.MapFile{Data: []byte("insert into test_table values (1);")}
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	history, err = dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	for _, hist := range history {
+		assert.Equal(t, MigrationSucceeded, hist.Status)
+	}
+}
+
+func TestDejaVu_Upgrade_Tables(t *testing.T) {
+	db, syntax := sqlite(t)
+	clock := newTestClock()
+	logger := newTestLogger(t)
+	stmts := DefaultStatements{}.
+		WithQuotes(QuotesSQLite()).
+		WithSchema("main").
+		WithHistoryTable("billing_history").
+		WithLockTable("billing_lock").
+		WithMetadataTable("billing_metadata")
+	database := NewDatabase(clock, logger, "sqlite", NewRepository(db, logger, syntax), stmts)
+	dv := NewConfig(database, newTestMigrations(t)).
+		WithClock(clock).
+		WithLogger(logger).
+		Build()
+	ctx := context.Background()
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	for _, table := range []string{"deja_vu_history", "deja_vu_lock"} {
+		exist, err := database.Exist(ctx, table)
+		require.NoError(t, err)
+		assert.False(t, exist, table)
+	}
+
+	count, err := database.Count(ctx, stmts.HistoryTable())
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = database.Count(ctx, stmts.LockTable())
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestDejaVu_Upgrade_Namespaces(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	billing := newTestConfig(t, db, "sqlite", syntax).WithNamespace("billing").Build()
+	billing.migs = FsMigrations{fs: fstest.MapFS{
+		"01_init.sql": {Data: []byte("create table invoice (id int not null);")},
+	}}
+	shipping := newTestConfig(t, db, "sqlite", syntax).WithNamespace("shipping").Build()
+	shipping.migs = FsMigrations{fs: fstest.MapFS{
+		"01_init.sql": {Data: []byte("create table parcel (id int not null);")},
+		"02_more.sql": {Data: []byte("create table carrier (id int not null);")},
+	}}
+
+	require.NoError(t, billing.Upgrade(ctx))
+	require.NoError(t, shipping.Upgrade(ctx))
+
+	history, err := billing.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "billing", history[0].Namespace)
+
+	history, err = shipping.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "shipping", history[0].Namespace)
+
+	missing, err := billing.Missing(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestDejaVu_Upgrade_NamespaceInitLock(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	billing := newTestConfig(t, db, "sqlite", syntax).
+		WithNamespace("billing").
+		WithClock(NewUtcClock()).
+		WithTick(time.Millisecond).
+		WithTimeout(50 * time.Millisecond).
+		Build()
+
+	require.NoError(t, billing.db.InitLock(ctx))
+
+	_, err := db.ExecContext(ctx,
+		"insert into deja_vu_lock (id, hostname, pid, since) values (?, ?, ?, current_timestamp)",
+		lockID,
+		"live-pod",
+		42,
+	)
+	require.NoError(t, err)
+
+	err = billing.Upgrade(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "last held by host live-pod with PID 42")
+
+	database, ok := billing.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	exist, err := database.Exist(ctx, "deja_vu_history")
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	_, err = db.ExecContext(ctx, "delete from deja_vu_lock")
+	require.NoError(t, err)
+
+	require.NoError(t, billing.Upgrade(ctx))
+
+	var count int
+
+	require.NoError(t, db.QueryRowContext(ctx, "select count(*) from deja_vu_lock").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestDejaVu_Upgrade_StaleLock(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).
+		WithClock(NewUtcClock()).
+		WithTick(time.Millisecond).
+		WithTimeout(50 * time.Millisecond).
+		Build()
+
+	require.NoError(t, dv.db.InitLock(ctx))
+
+	_, err := db.ExecContext(ctx,
+		"insert into deja_vu_lock (id, hostname, pid, since) values (?, ?, ?, ?)",
+		lockID,
+		"dead-pod",
+		42,
+		"2020-01-01 00:00:00",
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	_, err = db.ExecContext(ctx,
+		"insert into deja_vu_lock (id, hostname, pid, since) values (?, ?, ?, current_timestamp)",
+		lockID,
+		"live-pod",
+		42,
+	)
+	require.NoError(t, err)
+
+	err = dv.Upgrade(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "last held by host live-pod with PID 42")
+
+	dv.ttl = 0
+
+	_, err = db.ExecContext(ctx, "update deja_vu_lock set since = ?", "2020-01-01 00:00:00")
+	require.NoError(t, err)
+
+	require.Error(t, dv.Upgrade(ctx))
+}
+
+func TestDejaVu_ForceUnlock(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+
+	require.NoError(t, dv.db.InitLock(ctx))
+
+	_, found, err := dv.LockStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = db.ExecContext(ctx,
+		"insert into deja_vu_lock (id, hostname, pid, since) values (?, ?, ?, datetime(current_timestamp, '-90 seconds'))",
+		lockID,
+		"stuck-pod",
+		42,
+	)
+	require.NoError(t, err)
+
+	holder, found, err := dv.LockStatus(ctx)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "stuck-pod", holder.Hostname())
+	assert.Equal(t, 42, holder.Pid())
+	assert.False(t, holder.Since().IsZero())
+	assert.GreaterOrEqual(t, holder.Age(), 90*time.Second)
+	assert.Less(t, holder.Age(), 2*time.Minute)
+
+	_, _, err = dv.ForceUnlock(ctx, false)
+	require.Error(t, err)
+
+	_, found, err = dv.LockStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	holder, found, err = dv.ForceUnlock(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "stuck-pod", holder.Hostname())
+
+	_, found, err = dv.LockStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	dv.locker = sqliteLocker(t)
+
+	_, _, err = dv.LockStatus(ctx)
+	require.Error(t, err)
+}
+
+type cancelingMigrations struct {
+	FsMigrations
+
+	cancel context.CancelFunc
+	on     string
+}
+
+func (m cancelingMigrations) Content(name string) (string, error) {
+	if name == m.on {
+		m.cancel()
+	}
+
+	return m.FsMigrations.Content(name)
+}
+
+type lockStealingMigrations struct {
+	FsMigrations
+
+	db *sql.DB
+	on string
+}
+
+func (m lockStealingMigrations) Content(name string) (string, error) {
+	if name == m.on {
+		if _, err := m.db.Exec("delete from deja_vu_lock"); err != nil {
+			return "", err
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return m.FsMigrations.Content(name)
+}
+
+func TestDejaVu_Upgrade_LockLost(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).WithHeartbeat(time.Millisecond).Build()
+	dv.migs = lockStealingMigrations{
+		FsMigrations: FsMigrations{fs: fstest.MapFS{
+			"01_create_table.sql": {Data: []byte("create table test_table (id int not null);")},
+			"02_insert.sql":       {Data: []byte("insert into test_table values (1);")},
+		}},
+		db: db,
+		on: "02_insert.sql",
+	}
+
+	err := dv.Upgrade(ctx)
+	require.ErrorIs(t, err, ErrLockLost)
+	assert.Contains(t, err.Error(), "after completing 1 migration(s) 01_create_table.sql")
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	count, err := database.Count(ctx, "test_table")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestDejaVu_Upgrade_Canceled(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	dv.migs = cancelingMigrations{
+		FsMigrations: FsMigrations{fs: fstest.MapFS{
+			"01_create_table.sql": {Data: []byte("create table test_table (id int not null);")},
+			"02_insert.sql":       {Data: []byte("insert into test_table values (1);")},
+			"03_insert.sql":       {Data: []byte("insert into test_table values (2);")},
+		}},
+		cancel: cancel,
+		on:     "02_insert.sql",
+	}
+
+	err := dv.Upgrade(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "after completing 1 migration(s) 01_create_table.sql")
+
+	ctx = context.Background()
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	count, err := database.Count(ctx, "deja_vu_lock")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = database.Count(ctx, "test_table")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	status, err := dv.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.Equal(t, MigrationSucceeded, status[0].Status)
+	assert.Equal(t, MigrationFailed, status[1].Status)
+	assert.Equal(t, MigrationPending, status[2].Status)
+}
+
+func TestDejaVu_Upgrade_Renamed(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	dv.migs = NewFsMigrations(fstest.MapFS{
+		"01_create_contry_table.sql": {Data: []byte("create table country (id int not null);")},
+		"02_insert.sql":              {Data: []byte("insert into country values (1);")},
+	}).WithVersioning(NumericVersioning())
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	dv.migs = NewFsMigrations(fstest.MapFS{
+		"2023-01-01/01_create_country_table.sql": {Data: []byte("create table country (id int not null);")},
+		"2023-01-01/02_insert.sql":               {Data: []byte("insert into country values (1);")},
+		"2023-02-01/03_insert.sql":               {Data: []byte("insert into country values (2);")},
+	}).WithVersioning(NumericVersioning())
+
+	missing, err := dv.Missing(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2023-02-01/03_insert.sql"}, missing)
+
+	status, err := dv.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.Equal(t, "01_create_contry_table.sql", status[0].Name)
+	assert.Equal(t, MigrationSucceeded, status[1].Status)
+	assert.Equal(t, MigrationPending, status[2].Status)
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "2023-01-01/01_create_country_table.sql", history[0].Name)
+	assert.Equal(t, "2023-01-01/02_insert.sql", history[1].Name)
+	assert.Equal(t, "2023-02-01/03_insert.sql", history[2].Name)
+}
+
+func TestDejaVu_Upgrade_Aliases(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	dv.migs = FsMigrations{fs: fstest.MapFS{
+		"01_create_contry_table.sql": {Data: []byte("create table country (id int not null);")},
+	}}
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	dv.migs = FsMigrations{fs: fstest.MapFS{
+		"01_create_country_table.sql": {Data: []byte("create table country (id int not null);")},
+	}}
+
+	_, err := dv.Missing(ctx)
+	require.ErrorContains(t, err, "mismatch between history 01_create_contry_table.sql")
+
+	dv.aliases = map[string]string{"01_create_contry_table.sql": "01_create_country_table.sql"}
+
+	missing, err := dv.Missing(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "01_create_country_table.sql", history[0].Name)
+}
+
+func TestDejaVu_Upgrade_RenameConflict(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	dv.migs = FsMigrations{fs: fstest.MapFS{
+		"01_create_table.sql": {Data: []byte("create table test_table (id int not null);")},
+		"02_create_table.sql": {Data: []byte("create table other_table (id int not null);")},
+	}}
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	dv.aliases = map[string]string{"01_create_table.sql": "02_create_table.sql"}
+
+	require.ErrorContains(t, dv.Upgrade(ctx), "failed to rename migration 01_create_table.sql to 02_create_table.sql")
+}
+
+func TestDejaVu_Upgrade_Unit(t *testing.T) {
+	db, syntax := sqlite(t)
+	ctx := context.Background()
+	dv := newTestConfig(t, db, "sqlite", syntax).Build()
+	dv.migs = NewFsMigrations(fstest.MapFS{
+		"01_create_table.sql":          {Data: []byte("create table test_table (id int not null);")},
+		"02_unit/dejavu.manifest":      {},
+		"02_unit/01_alter.sql":         {Data: []byte("alter table test_table add name text;")},
+		"02_unit/02_insert.sql":        {Data: []byte("insert into test_table values (1, 'one');")},
+		"02_unit/03_insert_broken.sql": {Data: []byte("insert into missing_table values (2);")},
+	})
+
+	require.Error(t, dv.Upgrade(ctx))
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+	assert.False(t, database.HasColumn(ctx, "test_table", "name"))
+
+	dv.migs = NewFsMigrations(fstest.MapFS{
+		"01_create_table.sql":     {Data: []byte("create table test_table (id int not null);")},
+		"02_unit/dejavu.manifest": {},
+		"02_unit/01_alter.sql":    {Data: []byte("alter table test_table add name text;")},
+		"02_unit/02_insert.sql":   {Data: []byte("insert into test_table values (1, 'one');")},
+	})
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	count, err := database.Count(ctx, "test_table")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	content, err := dv.migs.Content("02_unit")
+	require.NoError(t, err)
+
+	history, err := dv.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "02_unit", history[1].Name)
+	assert.Equal(t, checksum(content), history[1].Checksum)
+}
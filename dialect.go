@@ -0,0 +1,149 @@
+package dejavu
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+const (
+	DialectNameMySQL      = "mysql"
+	DialectNameOracle     = "oracle"
+	DialectNamePostgreSQL = "postgresql"
+	DialectNameSQLite     = "sqlite"
+	DialectNameSQLServer  = "sqlserver"
+)
+
+var (
+	dialects = map[string]Dialect{
+		"github.com/denisenkom/go-mssqldb.Driver":    DialectSQLServer(),
+		"github.com/go-sql-driver/mysql.MySQLDriver": DialectMySQL(),
+		"github.com/jackc/pgx/v4/stdlib.Driver":      DialectPostgreSQL(),
+		"github.com/jackc/pgx/v5/stdlib.Driver":      DialectPostgreSQL(),
+		"github.com/lib/pq.Driver":                   DialectPostgreSQL(),
+		"github.com/mattn/go-sqlite3.SQLiteDriver":   DialectSQLite(),
+		"github.com/microsoft/go-mssqldb.Driver":     DialectSQLServer(),
+		"github.com/sijms/go-ora/v2.OracleDriver":    DialectOracle(),
+		"modernc.org/sqlite.Driver":                  DialectSQLite(),
+	}
+	dialectsMu sync.RWMutex
+)
+
+type Dialect struct {
+	Name         string
+	Placeholders Placeholders
+	Statements   func(stmts DefaultStatements) Statements
+}
+
+func DialectMySQL() Dialect {
+	return Dialect{
+		Name:         DialectNameMySQL,
+		Placeholders: PlaceholdersMySQL(),
+		Statements: func(stmts DefaultStatements) Statements {
+			return NewMySQLStatements(stmts)
+		},
+	}
+}
+
+func DialectOracle() Dialect {
+	return Dialect{
+		Name:         DialectNameOracle,
+		Placeholders: PlaceholdersOracle(),
+		Statements: func(stmts DefaultStatements) Statements {
+			return NewOracleStatements(stmts)
+		},
+	}
+}
+
+func DialectPostgreSQL() Dialect {
+	return Dialect{
+		Name:         DialectNamePostgreSQL,
+		Placeholders: PlaceholdersPostgreSQL(),
+		Statements: func(stmts DefaultStatements) Statements {
+			return NewPostgreSQLStatements(stmts)
+		},
+	}
+}
+
+func DialectSQLite() Dialect {
+	return Dialect{
+		Name:         DialectNameSQLite,
+		Placeholders: PlaceholdersSQLite(),
+		Statements: func(stmts DefaultStatements) Statements {
+			return NewSQLiteStatements(stmts)
+		},
+	}
+}
+
+func DialectSQLServer() Dialect {
+	return Dialect{
+		Name:         DialectNameSQLServer,
+		Placeholders: PlaceholdersSQLServer(),
+		Statements: func(stmts DefaultStatements) Statements {
+			return NewSQLServerStatements(stmts)
+		},
+	}
+}
+
+func RegisterDialect(drv driver.Driver, dialect Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+
+	dialects[driverName(drv)] = dialect
+}
+
+func DetectDialect(db *sql.DB) (Dialect, error) {
+	name := driverName(db.Driver())
+
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	dialect, found := dialects[name]
+	if !found {
+		return dialect, newError(nil, "unknown dialect for driver %s, use RegisterDialect or Dialect.NewDatabase", name)
+	}
+
+	return dialect, nil
+}
+
+func DetectDatabase(clock Clock, logger Logger, db *sql.DB, stmts DefaultStatements) (DefaultDatabase, error) {
+	dialect, err := DetectDialect(db)
+	if err != nil {
+		return DefaultDatabase{}, err
+	}
+
+	return dialect.NewDatabase(clock, logger, db, stmts), nil
+}
+
+func knownDialect(name string) bool {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	for _, dialect := range dialects {
+		if dialect.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d Dialect) NewDatabase(clock Clock, logger Logger, db *sql.DB, stmts DefaultStatements) DefaultDatabase {
+	return NewDatabase(clock, logger, d.Name, NewRepository(db, logger, d.Placeholders), d.Statements(stmts))
+}
+
+func (d Dialect) String() string {
+	return fmt.Sprintf("Dialect %s with %v", d.Name, d.Placeholders)
+}
+
+func driverName(drv driver.Driver) string {
+	t := reflect.TypeOf(drv)
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.PkgPath() + "." + t.Name()
+}
@@ -1,7 +1,10 @@
 package dejavu
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"time"
 )
@@ -11,16 +14,20 @@ const (
 )
 
 type Lock struct {
-	id       int
-	hostname string
-	pid      int
-	since    time.Time
+	age       time.Duration
+	id        int
+	namespace string
+	hostname  string
+	pid       int
+	since     time.Time
+	token     string
 }
 
-func NewLock() (Lock, error) {
+func NewLock(namespace string) (Lock, error) {
 	result := Lock{
-		id:  lockID,
-		pid: os.Getpid(),
+		id:        namespaceLockID(namespace),
+		namespace: namespace,
+		pid:       os.Getpid(),
 	}
 
 	hostname, err := os.Hostname()
@@ -30,9 +37,47 @@ func NewLock() (Lock, error) {
 
 	result.hostname = hostname
 
+	token := make([]byte, 16)
+	if _, err = rand.Read(token); err != nil {
+		return result, err
+	}
+
+	result.token = hex.EncodeToString(token)
+
 	return result, nil
 }
 
+func (l Lock) Namespace() string {
+	return l.namespace
+}
+
+func (l Lock) Hostname() string {
+	return l.hostname
+}
+
+func (l Lock) Pid() int {
+	return l.pid
+}
+
+func (l Lock) Since() time.Time {
+	return l.since
+}
+
+func (l Lock) Age() time.Duration {
+	return l.age
+}
+
 func (l Lock) String() string {
-	return fmt.Sprintf("Lock from host %s by PID %d since %v", l.hostname, l.pid, l.since)
+	return fmt.Sprintf("Lock %s from host %s by PID %d since %v", l.namespace, l.hostname, l.pid, l.since)
+}
+
+func namespaceLockID(namespace string) int {
+	if namespace == DefaultNamespace {
+		return lockID
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace))
+
+	return int(h.Sum32() & 0x7fffffff)
 }
@@ -0,0 +1,25 @@
+package dejavu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLock(t *testing.T) {
+	lck, err := NewLock(DefaultNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, lockID, lck.id)
+	assert.NotEmpty(t, lck.hostname)
+	assert.Len(t, lck.token, 32)
+
+	billing, err := NewLock("billing")
+	require.NoError(t, err)
+	assert.NotEqual(t, lck.id, billing.id)
+	assert.Positive(t, billing.id)
+
+	shipping, err := NewLock("shipping")
+	require.NoError(t, err)
+	assert.NotEqual(t, billing.id, shipping.id)
+}
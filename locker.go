@@ -0,0 +1,318 @@
+package dejavu
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	advisoryLockClass = 0x64656a61
+)
+
+type Locker interface {
+	fmt.Stringer
+
+	InitLock(ctx context.Context) error
+
+	Lock(ctx context.Context, lck Lock) bool
+	Refresh(ctx context.Context, lck Lock) error
+	Expire(ctx context.Context, lck Lock, ttl time.Duration) (Lock, bool, error)
+
+	Unlock(ctx context.Context, lck Lock) error
+}
+
+type advisoryLock interface {
+	fmt.Stringer
+
+	tryLock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error)
+	unlock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error)
+}
+
+func NewMySQLLocker(db *sql.DB, logger Logger) *ConnLocker {
+	return newConnLocker(db, logger, mysqlAdvisoryLock{logger: logger})
+}
+
+func NewOracleLocker(db *sql.DB, logger Logger) *ConnLocker {
+	return newConnLocker(db, logger, oracleUserLock{logger: logger})
+}
+
+func NewPostgreSQLLocker(db *sql.DB, logger Logger) *ConnLocker {
+	return newConnLocker(db, logger, pgAdvisoryLock{logger: logger})
+}
+
+func NewSQLServerLocker(db *sql.DB, logger Logger) *ConnLocker {
+	return newConnLocker(db, logger, sqlServerAppLock{logger: logger})
+}
+
+func NewSQLiteLocker(driverName, lockFile string, logger Logger) (*ConnLocker, error) {
+	db, err := sql.Open(driverName, lockFile)
+	if err != nil {
+		return nil, newError(err, "failed to open lock database %s", lockFile)
+	}
+
+	result := newConnLocker(db, logger, sqliteExclusiveLock{file: lockFile, logger: logger})
+	result.owned = true
+
+	return result, nil
+}
+
+func newConnLocker(db *sql.DB, logger Logger, advisory advisoryLock) *ConnLocker {
+	return &ConnLocker{
+		advisory: advisory,
+		conns:    make(map[string]*sql.Conn),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+type ConnLocker struct {
+	advisory advisoryLock
+	conns    map[string]*sql.Conn
+	db       *sql.DB
+	logger   Logger
+	mu       sync.Mutex
+	owned    bool
+}
+
+func (l *ConnLocker) InitLock(ctx context.Context) error {
+	if err := l.db.PingContext(ctx); err != nil {
+		return newError(err, "failed to ping lock database")
+	}
+
+	return nil
+}
+
+func (l *ConnLocker) Lock(ctx context.Context, lck Lock) bool {
+	l.logger.Log("Acquiring lock...")
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		l.logger.Log(fmt.Sprintf("failed to open lock connection: %v", err))
+
+		return false
+	}
+
+	locked, err := l.advisory.tryLock(ctx, conn, lck)
+	if err != nil || !locked {
+		if err != nil {
+			l.logger.Log(fmt.Sprintf("failed to acquire lock: %v", err))
+		}
+
+		_ = conn.Close()
+
+		return false
+	}
+
+	l.mu.Lock()
+	l.conns[lck.token] = conn
+	l.mu.Unlock()
+
+	l.logger.Log("Lock successfully acquired")
+
+	return true
+}
+
+func (l *ConnLocker) Refresh(context.Context, Lock) error {
+	return nil
+}
+
+func (l *ConnLocker) Expire(_ context.Context, lck Lock, _ time.Duration) (Lock, bool, error) {
+	return lck, false, nil
+}
+
+func (l *ConnLocker) Unlock(ctx context.Context, lck Lock) error {
+	l.logger.Log("Freeing lock...")
+
+	l.mu.Lock()
+	conn, found := l.conns[lck.token]
+	delete(l.conns, lck.token)
+	l.mu.Unlock()
+
+	if !found {
+		return newError(ErrLockLost, "failed to free lock held since %v", lck.since)
+	}
+
+	//goland:noinspection GoUnhandledErrorResult
+	defer conn.Close()
+
+	released, err := l.advisory.unlock(ctx, conn, lck)
+	if err != nil {
+		return newError(err, "failed to free lock")
+	}
+
+	if !released {
+		return newError(ErrLockLost, "failed to free lock held since %v", lck.since)
+	}
+
+	l.logger.Log("Lock successfully freed")
+
+	return nil
+}
+
+func (l *ConnLocker) Close() error {
+	if !l.owned {
+		return nil
+	}
+
+	return l.db.Close()
+}
+
+func (l *ConnLocker) String() string {
+	return fmt.Sprintf("%v on dedicated connection", l.advisory)
+}
+
+type pgAdvisoryLock struct {
+	logger Logger
+}
+
+func (a pgAdvisoryLock) tryLock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	return queryBool(ctx, a.logger, conn, "select pg_try_advisory_lock($1, $2)", advisoryLockClass, lck.id)
+}
+
+func (a pgAdvisoryLock) unlock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	return queryBool(ctx, a.logger, conn, "select pg_advisory_unlock($1, $2)", advisoryLockClass, lck.id)
+}
+
+func (a pgAdvisoryLock) String() string {
+	return "PostgreSQL advisory lock"
+}
+
+type mysqlAdvisoryLock struct {
+	logger Logger
+}
+
+func (a mysqlAdvisoryLock) tryLock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	return queryBool(ctx, a.logger, conn, "select coalesce(get_lock(?, 0), 0) = 1", advisoryLockName(lck))
+}
+
+func (a mysqlAdvisoryLock) unlock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	return queryBool(ctx, a.logger, conn, "select coalesce(release_lock(?), 0) = 1", advisoryLockName(lck))
+}
+
+func (a mysqlAdvisoryLock) String() string {
+	return "MySQL named lock"
+}
+
+type oracleUserLock struct {
+	logger Logger
+}
+
+func (a oracleUserLock) tryLock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	result, err := execInt(ctx, a.logger, conn,
+		`declare
+			handle varchar2(128);
+		begin
+			dbms_lock.allocate_unique(:1, handle);
+			:2 := dbms_lock.request(handle, dbms_lock.x_mode, 0, false);
+		end;`,
+		advisoryLockName(lck),
+	)
+
+	return result == 0, err
+}
+
+func (a oracleUserLock) unlock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	result, err := execInt(ctx, a.logger, conn,
+		`declare
+			handle varchar2(128);
+		begin
+			dbms_lock.allocate_unique(:1, handle);
+			:2 := dbms_lock.release(handle);
+		end;`,
+		advisoryLockName(lck),
+	)
+
+	return result == 0, err
+}
+
+func (a oracleUserLock) String() string {
+	return "Oracle user lock"
+}
+
+type sqlServerAppLock struct {
+	logger Logger
+}
+
+func (a sqlServerAppLock) tryLock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	return queryBool(ctx, a.logger, conn,
+		`declare @result int;
+		exec @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+		select case when @result >= 0 then 1 else 0 end`,
+		advisoryLockName(lck),
+	)
+}
+
+func (a sqlServerAppLock) unlock(ctx context.Context, conn *sql.Conn, lck Lock) (bool, error) {
+	return queryBool(ctx, a.logger, conn,
+		`declare @result int;
+		exec @result = sp_releaseapplock @Resource = @p1, @LockOwner = 'Session';
+		select case when @result = 0 then 1 else 0 end`,
+		advisoryLockName(lck),
+	)
+}
+
+func (a sqlServerAppLock) String() string {
+	return "SQL Server application lock"
+}
+
+type sqliteExclusiveLock struct {
+	file   string
+	logger Logger
+}
+
+func (a sqliteExclusiveLock) tryLock(ctx context.Context, conn *sql.Conn, _ Lock) (bool, error) {
+	LogStatement(a.logger, "begin exclusive", nil)
+
+	if _, err := conn.ExecContext(ctx, "begin exclusive"); err != nil {
+		a.logger.Log(fmt.Sprintf("Lock database busy: %v", err))
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (a sqliteExclusiveLock) unlock(ctx context.Context, conn *sql.Conn, _ Lock) (bool, error) {
+	LogStatement(a.logger, "rollback", nil)
+
+	if _, err := conn.ExecContext(ctx, "rollback"); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (a sqliteExclusiveLock) String() string {
+	return fmt.Sprintf("SQLite exclusive transaction on %s", a.file)
+}
+
+func advisoryLockName(lck Lock) string {
+	return fmt.Sprintf("deja_vu_%d", lck.id)
+}
+
+func execInt(ctx context.Context, logger Logger, conn *sql.Conn, query string, args ...any) (int64, error) {
+	result := int64(-1)
+
+	LogStatement(logger, query, args)
+
+	if _, err := conn.ExecContext(ctx, query, append(args, sql.Out{Dest: &result})...); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func queryBool(ctx context.Context, logger Logger, conn *sql.Conn, query string, args ...any) (bool, error) {
+	var result bool
+
+	LogStatement(logger, query, args)
+
+	if err := conn.QueryRowContext(ctx, query, args...).Scan(&result); err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
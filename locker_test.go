@@ -0,0 +1,120 @@
+package dejavu
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sqliteLocker(t *testing.T) *ConnLocker {
+	t.Helper()
+
+	result, err := NewSQLiteLocker("sqlite", filepath.Join(t.TempDir(), "deja_vu.lock"), newTestLogger(t))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = result.Close()
+	})
+
+	return result
+}
+
+func TestConnLocker(t *testing.T) {
+	type test struct {
+		name  string
+		setup func(t *testing.T) *ConnLocker
+	}
+
+	tests := []test{
+		{
+			name:  "sqlite",
+			setup: sqliteLocker,
+		},
+	}
+
+	if !testing.Short() {
+		tests = append(tests,
+			test{name: "mysql", setup: func(t *testing.T) *ConnLocker {
+				t.Helper()
+
+				db, _ := mysql(t)
+
+				return NewMySQLLocker(db, newTestLogger(t))
+			}},
+			test{name: "postgresql", setup: func(t *testing.T) *ConnLocker {
+				t.Helper()
+
+				db, _ := postgresql(t)
+
+				return NewPostgreSQLLocker(db, newTestLogger(t))
+			}},
+		)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locker := tt.setup(t)
+			ctx := context.Background()
+
+			require.NoError(t, locker.InitLock(ctx))
+
+			lck, err := NewLock(DefaultNamespace)
+			require.NoError(t, err)
+
+			other, err := NewLock(DefaultNamespace)
+			require.NoError(t, err)
+
+			require.True(t, locker.Lock(ctx, lck))
+			assert.False(t, locker.Lock(ctx, other))
+			require.NoError(t, locker.Refresh(ctx, lck))
+
+			_, expired, err := locker.Expire(ctx, other, 0)
+			require.NoError(t, err)
+			assert.False(t, expired)
+
+			require.ErrorIs(t, locker.Unlock(ctx, other), ErrLockLost)
+			require.NoError(t, locker.Unlock(ctx, lck))
+
+			require.True(t, locker.Lock(ctx, other))
+			require.NoError(t, locker.Unlock(ctx, other))
+		})
+	}
+}
+
+func TestDejaVu_Upgrade_Locker(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deja_vu.sqlite")
+
+	db, err := sql.Open("sqlite", file)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	locker, err := NewSQLiteLocker("sqlite", file+".lock", newTestLogger(t))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = locker.Close()
+	})
+
+	dv := newTestConfig(t, db, "sqlite", PlaceholdersSQLite()).WithLocker(locker).Build()
+	ctx := context.Background()
+
+	require.NoError(t, dv.Upgrade(ctx))
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	exist, err := database.Exist(ctx, "deja_vu_lock")
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	count, err := database.Count(ctx, "deja_vu_history")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
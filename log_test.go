@@ -19,3 +19,15 @@ func (l testLogger) Log(s string) {
 func (l testLogger) String() string {
 	return "test logger"
 }
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Log(s string) {
+	l.lines = append(l.lines, s)
+}
+
+func (l *recordingLogger) String() string {
+	return "recording logger"
+}
@@ -2,16 +2,76 @@ package dejavu
 
 import (
 	"fmt"
+	"path"
+	"strings"
 	"time"
 )
 
+type MigrationStatus string
+
+const (
+	MigrationFailed    MigrationStatus = "failed"
+	MigrationPending   MigrationStatus = "pending"
+	MigrationSucceeded MigrationStatus = "succeeded"
+)
+
 type Migration struct {
 	Name       string
 	Start      time.Time
 	DurationMs int64
 	Checksum   string
+	Status     MigrationStatus
+	Error      string
+
+	Namespace     string
+	InstalledRank int
+	Description   string
+	Hostname      string
+	Pid           int
+	OsUser        string
+	DBUser        string
+	Version       string
+	Progress      int
+}
+
+func (m Migration) Failed() bool {
+	return m.Status == MigrationFailed
 }
 
 func (m Migration) String() string {
+	switch m.Status {
+	case MigrationFailed:
+		return fmt.Sprintf("Migration %s started at %v failed after %d: %s", m.Name, m.Start, m.DurationMs, m.Error)
+	case MigrationPending:
+		return fmt.Sprintf("Migration %s pending", m.Name)
+	case MigrationSucceeded:
+	}
+
 	return fmt.Sprintf("Migration %s started at %v last %d", m.Name, m.Start, m.DurationMs)
 }
+
+func Describe(name string) string {
+	base := migrationBase(name)
+
+	if i := strings.Index(base, "_"); i != -1 && strings.Trim(base[:i], "0123456789") == "" {
+		base = base[i+1:]
+	}
+
+	return strings.ReplaceAll(base, "_", " ")
+}
+
+func DescribeVersion(name string, version Version) string {
+	base := migrationBase(name)
+
+	if version.prefix == "" || !strings.HasPrefix(base, version.prefix) {
+		return Describe(name)
+	}
+
+	return strings.ReplaceAll(strings.TrimLeft(strings.TrimPrefix(base, version.prefix), "_"), "_", " ")
+}
+
+func migrationBase(name string) string {
+	base, _ := trimDialectTags(strings.TrimSuffix(path.Base(name), ".sql"))
+
+	return base
+}
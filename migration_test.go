@@ -0,0 +1,80 @@
+package dejavu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "2023-01-01/01_create_country_table.postgresql.sql",
+			want: "create country table",
+		},
+		{
+			name: "02_create_country_index.sql",
+			want: "create country index",
+		},
+		{
+			name: "populate_country_table.sql",
+			want: "populate country table",
+		},
+		{
+			name: "03_seed_countries.mysql",
+			want: "seed countries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Describe(tt.name))
+		})
+	}
+}
+
+func TestDescribeVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning Versioning
+		want       string
+	}{
+		{
+			name:       "V1.2.3__add_index.sql",
+			versioning: SemanticVersioning(),
+			want:       "add index",
+		},
+		{
+			name:       "1.10.0-rc.1_create_table.postgresql.sql",
+			versioning: SemanticVersioning(),
+			want:       "create table",
+		},
+		{
+			name:       "20230101120000_create_table.sql",
+			versioning: TimestampVersioning(),
+			want:       "create table",
+		},
+		{
+			name:       "2023-01-01/01_create_index.sql",
+			versioning: DateFolderVersioning(),
+			want:       "create index",
+		},
+		{
+			name:       "01_create_table.sql",
+			versioning: LexicalVersioning(),
+			want:       "create table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := tt.versioning.Parse(tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, DescribeVersion(tt.name, version))
+		})
+	}
+}
@@ -1,9 +1,28 @@
 package dejavu
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+)
+
+const (
+	CompressedExt = ".gz"
+	IgnoreFile    = ".dejavuignore"
+	ManifestFile  = "dejavu.manifest"
+)
+
+var (
+	dialectTag = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+	migrationPatterns   = []string{"*.sql", "*.sql" + CompressedExt}
+	migrationPatternsMu sync.RWMutex
 )
 
 type Migrations interface {
@@ -13,32 +32,241 @@ type Migrations interface {
 	Content(name string) (string, error)
 }
 
+type VersionedMigrations interface {
+	Migrations
+
+	Version(name string) (Version, error)
+}
+
+func NewFsMigrations(fsys fs.FS) FsMigrations {
+	return FsMigrations{fs: fsys}
+}
+
 type FsMigrations struct {
-	fs fs.FS
+	fs         fs.FS
+	includes   []string
+	logger     Logger
+	versioning Versioning
+}
+
+func RegisterMigrationPattern(pattern string) {
+	migrationPatternsMu.Lock()
+	defer migrationPatternsMu.Unlock()
+
+	if !slices.Contains(migrationPatterns, pattern) {
+		migrationPatterns = append(migrationPatterns, pattern)
+	}
+}
+
+func MigrationPatterns() []string {
+	migrationPatternsMu.RLock()
+	defer migrationPatternsMu.RUnlock()
+
+	return slices.Clone(migrationPatterns)
+}
+
+func (m FsMigrations) WithIncludes(patterns ...string) FsMigrations {
+	m.includes = patterns
+
+	return m
+}
+
+func (m FsMigrations) WithLogger(logger Logger) FsMigrations {
+	m.logger = logger
+
+	return m
+}
+
+func (m FsMigrations) WithVersioning(versioning Versioning) FsMigrations {
+	m.versioning = versioning
+
+	return m
+}
+
+func (m FsMigrations) Version(name string) (Version, error) {
+	if m.versioning == nil {
+		return LexicalVersioning().Parse(name)
+	}
+
+	return m.versioning.Parse(name)
 }
 
 func (m FsMigrations) List(database string) ([]string, error) {
-	result := make([]string, 0)
-	err := fs.WalkDir(m.fs, ".", func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
+	includes := m.includePatterns()
+
+	ignores, err := m.ignorePatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	generic := make(map[string]string)
+	specific := make(map[string]string)
+	err = fs.WalkDir(m.fs, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || name == "." || name == IgnoreFile {
 			return err
 		}
 
-		if FilterMigration(entry.Name(), database) {
+		if matchAny(ignores, name, entry.IsDir()) {
+			m.log(fmt.Sprintf("Skipping ignored %s", name))
+
 			if entry.IsDir() {
 				return fs.SkipDir
 			}
-		} else if !entry.IsDir() {
-			result = append(result, path)
+
+			return nil
+		}
+
+		if !entry.IsDir() && !matchAny(includes, name, false) {
+			m.log(fmt.Sprintf("Skipping non migration file %s", name))
+
+			return nil
+		}
+
+		unit := entry.IsDir() && m.isUnit(name)
+		if entry.IsDir() && !unit {
+			return nil
+		}
+
+		mig := name
+
+		var (
+			base string
+			tags []string
+		)
+
+		if unit {
+			base, tags = trimDialectTags(entry.Name())
+		} else {
+			mig = strings.TrimSuffix(name, CompressedExt)
+			base, tags = trimDialectTags(strings.TrimSuffix(path.Base(mig), path.Ext(mig)))
+		}
+
+		if err = checkDialectTags(name, tags, database); err != nil {
+			return err
+		}
+
+		if len(tags) > 0 && !slices.Contains(tags, database) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if unit {
+			if _, err = m.unitFiles(name); err != nil {
+				return err
+			}
+		}
+
+		key := path.Join(path.Dir(name), base)
+
+		migs := specific
+		if len(tags) == 0 {
+			migs = generic
+		}
+
+		if previous, found := migs[key]; found {
+			return newError(nil, "duplicate %s migrations %s and %s", database, previous, name)
+		}
+
+		migs[key] = mig
+
+		if entry.IsDir() {
+			return fs.SkipDir
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(generic)+len(specific))
+
+	for key, mig := range generic {
+		if _, found := specific[key]; !found {
+			result = append(result, mig)
+		}
+	}
+
+	for _, mig := range specific {
+		result = append(result, mig)
+	}
+
+	sort.Strings(result)
+
+	return sortByVersion(result, m.versioning)
+}
+
+func (m FsMigrations) includePatterns() []string {
+	if len(m.includes) == 0 {
+		return MigrationPatterns()
+	}
+
+	return m.includes
+}
+
+func (m FsMigrations) ignorePatterns() ([]string, error) {
+	data, err := fs.ReadFile(m.fs, IgnoreFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, newError(err, "failed to read %s", IgnoreFile)
+	}
+
+	result := make([]string, 0)
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, err = path.Match(strings.Trim(line, "/"), ""); err != nil {
+			return nil, newError(err, "invalid pattern %s at line %d of %s", line, i+1, IgnoreFile)
+		}
+
+		result = append(result, line)
+	}
 
-	return result, err
+	return result, nil
+}
+
+func (m FsMigrations) log(s string) {
+	if m.logger != nil {
+		m.logger.Log(s)
+	}
 }
 
 func (m FsMigrations) Content(name string) (string, error) {
+	if m.isUnit(name) {
+		return m.unitContent(name)
+	}
+
+	data, err := m.readFile(name)
+	if errors.Is(err, fs.ErrNotExist) && !strings.HasSuffix(name, CompressedExt) {
+		name += CompressedExt
+
+		data, err = m.readFile(name)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(name, CompressedExt) {
+		if data, err = gunzip(data); err != nil {
+			return "", newError(err, "failed to decompress migration %s", name)
+		}
+	}
+
+	return string(data), nil
+}
+
+func (m FsMigrations) readFile(name string) ([]byte, error) {
 	var err error
 
 	fsys := m.fs
@@ -47,25 +275,217 @@ func (m FsMigrations) Content(name string) (string, error) {
 	for _, path := range paths[:len(paths)-1] {
 		fsys, err = fs.Sub(fsys, path)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	data, err := fs.ReadFile(fsys, paths[len(paths)-1])
+	return fs.ReadFile(fsys, paths[len(paths)-1])
+}
+
+func (m FsMigrations) isUnit(name string) bool {
+	info, err := fs.Stat(m.fs, path.Join(name, ManifestFile))
+
+	return err == nil && !info.IsDir()
+}
+
+func (m FsMigrations) unitContent(name string) (string, error) {
+	files, err := m.unitFiles(name)
 	if err != nil {
 		return "", err
 	}
 
-	return string(data), nil
+	if len(files) == 0 {
+		return "", newError(nil, "no files in migration %s", name)
+	}
+
+	var sb strings.Builder
+
+	for _, file := range files {
+		content, err := m.Content(path.Join(name, file))
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(content)
+
+		if !strings.HasSuffix(content, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func (m FsMigrations) unitFiles(name string) ([]string, error) {
+	data, err := fs.ReadFile(m.fs, path.Join(name, ManifestFile))
+	if err != nil {
+		return nil, newError(err, "failed to read manifest of migration %s", name)
+	}
+
+	result := make([]string, 0)
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !fs.ValidPath(line) {
+			return nil, newError(nil, "invalid file %s at line %d of manifest of migration %s", line, i+1, name)
+		}
+
+		result = append(result, line)
+	}
+
+	if len(result) == 0 {
+		if result, err = m.unitEntries(name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range result {
+		if _, tags := dialectTags(path.Base(strings.TrimSuffix(file, CompressedExt))); len(tags) > 0 {
+			return nil, newError(nil,
+				"dialect tagged file %s in migration %s, tag the migration directory instead",
+				file,
+				name,
+			)
+		}
+	}
+
+	return result, nil
+}
+
+func (m FsMigrations) unitEntries(name string) ([]string, error) {
+	result := make([]string, 0)
+
+	entries, err := fs.ReadDir(m.fs, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ignores, err := m.ignorePatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	includes := m.includePatterns()
+
+	for _, entry := range entries {
+		file := path.Join(name, entry.Name())
+
+		if entry.IsDir() || matchAny(ignores, file, false) || !matchAny(includes, file, false) {
+			continue
+		}
+
+		result = append(result, entry.Name())
+	}
+
+	return result, nil
 }
 
 func (m FsMigrations) String() string {
 	return fmt.Sprintf("%v", m.fs)
 }
 
+func matchAny(patterns []string, name string, dir bool) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name, dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchPattern(pattern, name string, dir bool) bool {
+	if strings.HasSuffix(pattern, "/") {
+		if !dir {
+			return false
+		}
+
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	target := path.Base(name)
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+		target = name
+	}
+
+	matched, _ := path.Match(pattern, target)
+
+	return matched
+}
+
 func FilterMigration(migName, targetDatabase string) bool {
-	parts := strings.Split(migName, ".")
-	l := len(parts)
+	_, tags := dialectTags(migName)
+
+	return len(tags) > 0 && !slices.Contains(tags, targetDatabase)
+}
+
+func dialectTags(name string) (string, []string) {
+	ext := path.Ext(name)
+	base, tags := trimDialectTags(strings.TrimSuffix(name, ext))
+
+	return base + ext, tags
+}
+
+func trimDialectTags(name string) (string, []string) {
+	parts := strings.Split(name, ".")
+	i := len(parts)
+
+	for i > 1 && isDialectTag(parts[i-1]) {
+		i--
+	}
+
+	return strings.Join(parts[:i], "."), parts[i:]
+}
+
+func checkDialectTags(name string, tags []string, database string) error {
+	for _, tag := range tags {
+		if tag != database && !knownDialect(tag) {
+			return newError(nil, "unknown dialect tag %s in migration %s", tag, name)
+		}
+	}
+
+	return nil
+}
+
+func isDialectTag(s string) bool {
+	return dialectTag.MatchString(s)
+}
+
+func sortByVersion(names []string, versioning Versioning) ([]string, error) {
+	if versioning == nil {
+		versioning = LexicalVersioning()
+	}
+
+	versions := make(map[string]Version, len(names))
+
+	for _, name := range names {
+		version, err := versioning.Parse(name)
+		if err != nil {
+			return nil, err
+		}
+
+		versions[name] = version
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return versions[names[i]].Compare(versions[names[j]]) < 0
+	})
+
+	for i := 1; i < len(names); i++ {
+		if versions[names[i-1]].Compare(versions[names[i]]) == 0 {
+			return nil, newError(nil,
+				"duplicate version %v for migrations %s and %s",
+				versions[names[i]],
+				names[i-1],
+				names[i],
+			)
+		}
+	}
 
-	return l > 2 && parts[l-2] != targetDatabase
+	return names, nil
 }
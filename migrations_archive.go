@@ -0,0 +1,114 @@
+package dejavu
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+type archiveFS struct {
+	fs.FS
+
+	name string
+}
+
+func (a archiveFS) String() string {
+	return fmt.Sprintf("archive %s", a.name)
+}
+
+func OpenArchiveMigrations(name string) (FsMigrations, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return FsMigrations{}, newError(err, "failed to read archive %s", name)
+	}
+
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return newZipMigrations(name, bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return newTarGzMigrations(name, bytes.NewReader(data))
+	}
+
+	return FsMigrations{}, newError(nil, "unsupported archive %s, expected .zip, .tar.gz or .tgz", name)
+}
+
+func NewZipMigrations(r io.ReaderAt, size int64) (FsMigrations, error) {
+	return newZipMigrations("zip", r, size)
+}
+
+func NewTarGzMigrations(r io.Reader) (FsMigrations, error) {
+	return newTarGzMigrations("tar.gz", r)
+}
+
+func newZipMigrations(name string, r io.ReaderAt, size int64) (FsMigrations, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return FsMigrations{}, newError(err, "failed to open zip archive %s", name)
+	}
+
+	return NewFsMigrations(archiveFS{FS: reader, name: name}), nil
+}
+
+func newTarGzMigrations(name string, r io.Reader) (FsMigrations, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return FsMigrations{}, newError(err, "failed to open tar.gz archive %s", name)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer gz.Close()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return FsMigrations{}, newError(err, "failed to read tar.gz archive %s", name)
+		}
+
+		file := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if hdr.Typeflag != tar.TypeReg || !fs.ValidPath(file) {
+			continue
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Store, Modified: hdr.ModTime})
+		if err != nil {
+			return FsMigrations{}, newError(err, "failed to unpack %s from tar.gz archive %s", file, name)
+		}
+
+		if _, err = io.Copy(w, tr); err != nil { //nolint:gosec
+			return FsMigrations{}, newError(err, "failed to unpack %s from tar.gz archive %s", file, name)
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return FsMigrations{}, newError(err, "failed to unpack tar.gz archive %s", name)
+	}
+
+	return newZipMigrations(name, bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer gz.Close()
+
+	return io.ReadAll(gz)
+}
@@ -0,0 +1,178 @@
+package dejavu
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+type CompositeMigrations struct {
+	sources    []migrationSource
+	versioning Versioning
+}
+
+type migrationSource struct {
+	prefix string
+	migs   Migrations
+}
+
+func NewCompositeMigrations() CompositeMigrations {
+	return CompositeMigrations{}
+}
+
+func (m CompositeMigrations) WithVersioning(versioning Versioning) CompositeMigrations {
+	m.versioning = versioning
+
+	return m
+}
+
+func (m CompositeMigrations) WithFS(prefix string, fsys fs.FS) CompositeMigrations {
+	return m.WithMigrations(prefix, NewFsMigrations(fsys))
+}
+
+func (m CompositeMigrations) WithMigrations(prefix string, migs Migrations) CompositeMigrations {
+	m.sources = append(m.sources[:len(m.sources):len(m.sources)], migrationSource{
+		prefix: strings.Trim(prefix, "/"),
+		migs:   migs,
+	})
+
+	return m
+}
+
+func (m CompositeMigrations) List(database string) ([]string, error) {
+	type entry struct {
+		name    string
+		version Version
+	}
+
+	versioning := m.versioning
+	if versioning == nil {
+		versioning = LexicalVersioning()
+	}
+
+	entries := make([]entry, 0)
+	sources := make(map[string]int)
+	ids := make(map[string]string)
+
+	for i, source := range m.sources {
+		names, err := source.migs.List(database)
+		if err != nil {
+			return nil, newError(err, "failed to list migrations of %v", source)
+		}
+
+		for _, name := range names {
+			fullName := source.name(name)
+
+			if previous, found := sources[fullName]; found {
+				return nil, newError(nil, "duplicate migration %s in %v and %v", fullName, m.sources[previous], source)
+			}
+
+			version, err := versioning.Parse(name)
+			if err != nil {
+				return nil, err
+			}
+
+			id := source.name(version.ID)
+
+			if previous, found := ids[id]; found {
+				return nil, newError(nil, "duplicate version %v for migrations %s and %s", version, previous, fullName)
+			}
+
+			ids[id] = fullName
+			sources[fullName] = i
+			entries = append(entries, entry{name: fullName, version: version})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].version.Compare(entries[j].version) < 0
+	})
+
+	result := make([]string, len(entries))
+
+	for i, e := range entries {
+		result[i] = e.name
+	}
+
+	return result, nil
+}
+
+func (m CompositeMigrations) Version(name string) (Version, error) {
+	versioning := m.versioning
+	if versioning == nil {
+		versioning = LexicalVersioning()
+	}
+
+	for _, source := range m.sources {
+		relative, found := source.relative(name)
+		if !found {
+			continue
+		}
+
+		version, err := versioning.Parse(relative)
+		if err != nil {
+			return version, err
+		}
+
+		version.ID = source.name(version.ID)
+
+		return version, nil
+	}
+
+	return Version{}, newError(nil, "unknown migration %s", name)
+}
+
+func (m CompositeMigrations) Content(name string) (string, error) {
+	for _, source := range m.sources {
+		relative, found := source.relative(name)
+		if !found {
+			continue
+		}
+
+		content, err := source.migs.Content(relative)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		return content, err
+	}
+
+	return "", newError(fs.ErrNotExist, "unknown migration %s", name)
+}
+
+func (m CompositeMigrations) String() string {
+	sources := make([]string, len(m.sources))
+
+	for i, source := range m.sources {
+		sources[i] = source.String()
+	}
+
+	return fmt.Sprintf("Composite migrations of %s", strings.Join(sources, ", "))
+}
+
+func (s migrationSource) name(relative string) string {
+	if s.prefix == "" {
+		return relative
+	}
+
+	return path.Join(s.prefix, relative)
+}
+
+func (s migrationSource) relative(name string) (string, bool) {
+	if s.prefix == "" {
+		return name, true
+	}
+
+	return strings.CutPrefix(name, s.prefix+"/")
+}
+
+func (s migrationSource) String() string {
+	if s.prefix == "" {
+		return fmt.Sprintf("%v", s.migs)
+	}
+
+	return fmt.Sprintf("%v in %s", s.migs, s.prefix)
+}
@@ -1,23 +1,34 @@
 package dejavu
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func newTestMigrations(t *testing.T) FsMigrations {
+func newTestFS(t *testing.T) fs.FS {
 	t.Helper()
 
 	root, err := fs.Sub(os.DirFS("testdata"), "db")
 	require.NoError(t, err)
 
-	return FsMigrations{
-		fs: root,
-	}
+	return root
+}
+
+func newTestMigrations(t *testing.T) FsMigrations {
+	t.Helper()
+
+	return NewFsMigrations(newTestFS(t))
 }
 
 func TestNewMigrations(t *testing.T) {
@@ -89,3 +100,452 @@ func Test_fsMigrations_Content(t *testing.T) {
 		})
 	}
 }
+
+func TestCompositeMigrations(t *testing.T) {
+	core := fstest.MapFS{
+		"2023-01-01/01_create_user.sql":  {Data: []byte("create table user (name text);")},
+		"2023-03-01/01_create_role.sql":  {Data: []byte("create table role (name text);")},
+		"2023-03-01/02_grant.sqlite.sql": {Data: []byte("insert into role values ('admin');")},
+	}
+	app := fstest.MapFS{
+		"2023-02-01/01_create_invoice.sql": {Data: []byte("create table invoice (id int);")},
+	}
+	migs := NewCompositeMigrations().WithFS("core", core).WithFS("", app)
+
+	names, err := migs.List("postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"core/2023-01-01/01_create_user.sql",
+		"2023-02-01/01_create_invoice.sql",
+		"core/2023-03-01/01_create_role.sql",
+	}, names)
+
+	content, err := migs.Content("core/2023-03-01/01_create_role.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "create table role (name text);", content)
+
+	content, err = migs.Content("2023-02-01/01_create_invoice.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "create table invoice (id int);", content)
+
+	_, err = migs.Content("core/2023-02-01/01_create_invoice.sql")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	assert.Contains(t, migs.String(), "] in core, map[")
+}
+
+func TestCompositeMigrations_Duplicate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"01_init.sql": {Data: []byte("select 1;")},
+	}
+
+	_, err := NewCompositeMigrations().WithFS("", fsys).WithFS("", fsys).List("sqlite")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate migration 01_init.sql")
+
+	names, err := NewCompositeMigrations().WithFS("core", fsys).WithFS("app", fsys).List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"core/01_init.sql", "app/01_init.sql"}, names)
+}
+
+func TestCompositeMigrations_Version(t *testing.T) {
+	fsys := fstest.MapFS{
+		"01_init.sql": {Data: []byte("select 1;")},
+	}
+	migs := NewCompositeMigrations().WithFS("core", fsys).WithFS("app", fsys).WithVersioning(NumericVersioning())
+
+	version, err := migs.Version("core/2023-01-01/01_create_user.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "core/1", version.ID)
+
+	version, err = migs.Version("app/01_init.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "app/1", version.ID)
+
+	_, err = migs.Version("billing/01_init.sql")
+	require.ErrorContains(t, err, "unknown migration billing/01_init.sql")
+}
+
+func TestFsMigrations_List_Override(t *testing.T) {
+	migs := NewFsMigrations(fstest.MapFS{
+		"01_create_table.sql":                   {Data: []byte("create table test_table (id int);")},
+		"01_create_table.postgresql.sql":        {Data: []byte("create table test_table (id serial);")},
+		"02_insert.sql":                         {Data: []byte("insert into test_table values (1);")},
+		"03_upsert.mysql.postgresql.sql":        {Data: []byte("insert into test_table values (2) on conflict do nothing;")},
+		"2023-01-01/01_create_index.sqlite.sql": {Data: []byte("create index test_index on test_table (id);")},
+	})
+
+	names, err := migs.List("postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"01_create_table.postgresql.sql",
+		"02_insert.sql",
+		"03_upsert.mysql.postgresql.sql",
+	}, names)
+
+	names, err = migs.List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"01_create_table.sql",
+		"02_insert.sql",
+		"2023-01-01/01_create_index.sqlite.sql",
+	}, names)
+
+	names, err = migs.List("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"01_create_table.sql",
+		"02_insert.sql",
+		"03_upsert.mysql.postgresql.sql",
+	}, names)
+}
+
+func TestFsMigrations_List_UnknownTag(t *testing.T) {
+	_, err := NewFsMigrations(fstest.MapFS{
+		"01_create_table.postgres.sql": {Data: []byte("create table test_table (id int);")},
+	}).List("postgresql")
+	require.ErrorContains(t, err, "unknown dialect tag postgres in migration 01_create_table.postgres.sql")
+
+	names, err := NewFsMigrations(fstest.MapFS{
+		"01_create_table.cockroach.sql": {Data: []byte("create table test_table (id int);")},
+	}).List("cockroach")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_create_table.cockroach.sql"}, names)
+
+	names, err = NewFsMigrations(fstest.MapFS{
+		"release.v1.0/01_create_table.sql": {Data: []byte("create table test_table (id int);")},
+	}).List("postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"release.v1.0/01_create_table.sql"}, names)
+}
+
+func TestFsMigrations_List_DuplicateOverride(t *testing.T) {
+	_, err := NewFsMigrations(fstest.MapFS{
+		"01_create_table.postgresql.sql":       {Data: []byte("create table test_table (id int);")},
+		"01_create_table.mysql.postgresql.sql": {Data: []byte("create table test_table (id int);")},
+	}).List("postgresql")
+	require.ErrorContains(t, err, "duplicate postgresql migrations")
+}
+
+func TestFsMigrations_List_Ignored(t *testing.T) {
+	logger := &recordingLogger{}
+	migs := NewFsMigrations(fstest.MapFS{
+		".dejavuignore":          {Data: []byte("# drafts are not ready\ndrafts/\n*_wip.sql\n\n/legacy/*.sql\n")},
+		".gitkeep":               {},
+		"README.md":              {Data: []byte("# Migrations")},
+		"01_init.sql":            {Data: []byte("select 1;")},
+		"01_init.sql~":           {Data: []byte("select 0;")},
+		"02_next_wip.sql":        {Data: []byte("select 2;")},
+		"drafts/03_later.sql":    {Data: []byte("select 3;")},
+		"legacy/00_old.sql":      {Data: []byte("select 0;")},
+		"sub/legacy/04_x.sql":    {Data: []byte("select 4;")},
+		"sub/README.postgres.md": {Data: []byte("# Postgres")},
+	}).WithLogger(logger)
+
+	names, err := migs.List("postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_init.sql", "sub/legacy/04_x.sql"}, names)
+	assert.Contains(t, logger.lines, "Skipping ignored drafts")
+	assert.Contains(t, logger.lines, "Skipping ignored 02_next_wip.sql")
+	assert.Contains(t, logger.lines, "Skipping ignored legacy/00_old.sql")
+	assert.Contains(t, logger.lines, "Skipping non migration file README.md")
+	assert.Contains(t, logger.lines, "Skipping non migration file 01_init.sql~")
+
+	names, err = migs.WithIncludes("*.sql", "*.md").List("postgresql")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown dialect tag postgres in migration sub/README.postgres.md")
+	assert.Nil(t, names)
+}
+
+func TestFsMigrations_List_InvalidIgnore(t *testing.T) {
+	_, err := NewFsMigrations(fstest.MapFS{
+		".dejavuignore": {Data: []byte("*.sql\n[\n")},
+	}).List("sqlite")
+	require.ErrorContains(t, err, "invalid pattern [ at line 2 of .dejavuignore")
+}
+
+func TestRegisterMigrationPattern(t *testing.T) {
+	fsys := fstest.MapFS{
+		"01_init.sql": {Data: []byte("select 1;")},
+		"02_next.ddl": {Data: []byte("select 2;")},
+	}
+
+	names, err := NewFsMigrations(fsys).WithIncludes("*.ddl").List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"02_next.ddl"}, names)
+
+	RegisterMigrationPattern("*.ddl")
+	t.Cleanup(func() {
+		migrationPatternsMu.Lock()
+		defer migrationPatternsMu.Unlock()
+
+		migrationPatterns = slices.DeleteFunc(migrationPatterns, func(pattern string) bool {
+			return pattern == "*.ddl"
+		})
+	})
+
+	assert.Contains(t, MigrationPatterns(), "*.ddl")
+
+	names, err = NewFsMigrations(fsys).List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_init.sql", "02_next.ddl"}, names)
+}
+
+func TestFsMigrations_Unit(t *testing.T) {
+	migs := NewFsMigrations(fstest.MapFS{
+		"01_create_country.sql":                   {Data: []byte("create table country (name text);")},
+		"02_add_code/dejavu.manifest":             {Data: []byte("# schema first\n02_backfill.sql\n01_alter.sql\n")},
+		"02_add_code/01_alter.sql":                {Data: []byte("alter table country add code text;")},
+		"02_add_code/02_backfill.sql":             {Data: []byte("update country set code = 'FR';\n")},
+		"02_add_code/03_index.sql":                {Data: []byte("create index country_code on country (code);")},
+		"03_add_capital/dejavu.manifest":          {},
+		"03_add_capital/01_alter.sql":             {Data: []byte("alter table country add capital text;")},
+		"03_add_capital/02_backfill.sql":          {Data: []byte("update country set capital = 'Paris';")},
+		"03_add_capital/README.md":                {Data: []byte("# Capital")},
+		"03_add_capital/drafts/01_population.sql": {Data: []byte("alter table country add population int;")},
+		"04_not_a_unit/01_insert.sql":             {Data: []byte("insert into country values ('Italy');")},
+	})
+
+	names, err := migs.List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"01_create_country.sql",
+		"02_add_code",
+		"03_add_capital",
+		"04_not_a_unit/01_insert.sql",
+	}, names)
+
+	content, err := migs.Content("02_add_code")
+	require.NoError(t, err)
+	assert.Equal(t, "update country set code = 'FR';\nalter table country add code text;\n", content)
+
+	content, err = migs.Content("03_add_capital")
+	require.NoError(t, err)
+	assert.Equal(t, "alter table country add capital text;\nupdate country set capital = 'Paris';\n", content)
+}
+
+func TestFsMigrations_Unit_Invalid(t *testing.T) {
+	migs := NewFsMigrations(fstest.MapFS{
+		"01_empty/dejavu.manifest":   {},
+		"02_escape/dejavu.manifest":  {Data: []byte("../01_empty/dejavu.manifest\n")},
+		"03_missing/dejavu.manifest": {Data: []byte("01_missing.sql\n")},
+	})
+
+	_, err := migs.Content("01_empty")
+	require.ErrorContains(t, err, "no files in migration 01_empty")
+
+	_, err = migs.Content("02_escape")
+	require.ErrorContains(t, err, "invalid file ../01_empty/dejavu.manifest at line 1 of manifest of migration 02_escape")
+
+	_, err = migs.Content("03_missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func gzipData(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func TestFsMigrations_Compressed(t *testing.T) {
+	migs := NewFsMigrations(fstest.MapFS{
+		"01_init.sql":                       {Data: []byte("create table country (name text);")},
+		"02_populate.sql.gz":                {Data: gzipData(t, "insert into country values ('France');")},
+		"03_unit.sqlite/dejavu.manifest":    {},
+		"03_unit.sqlite/01_populate.sql.gz": {Data: gzipData(t, "insert into country values ('Italy');")},
+		"04_broken.sql.gz":                  {Data: []byte("insert into country values ('Spain');")},
+	})
+
+	names, err := migs.List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_init.sql", "02_populate.sql", "03_unit.sqlite", "04_broken.sql"}, names)
+
+	content, err := migs.Content("02_populate.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "insert into country values ('France');", content)
+
+	content, err = migs.Content("03_unit.sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, "insert into country values ('Italy');\n", content)
+
+	_, err = migs.Content("04_broken.sql")
+	require.ErrorContains(t, err, "failed to decompress migration 04_broken.sql.gz")
+
+	_, err = NewFsMigrations(fstest.MapFS{
+		"01_init.sql":    {Data: []byte("select 1;")},
+		"01_init.sql.gz": {Data: gzipData(t, "select 1;")},
+	}).List("sqlite")
+	require.ErrorContains(t, err, "duplicate sqlite migrations 01_init.sql and 01_init.sql.gz")
+}
+
+func TestFsMigrations_Compressed_Testdata(t *testing.T) {
+	migs := NewFsMigrations(os.DirFS(filepath.Join("testdata", "compressed")))
+
+	names, err := migs.List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_populate_country_table.sql"}, names)
+
+	content, err := migs.Content("01_populate_country_table.sql")
+	require.NoError(t, err)
+	assert.Equal(t,
+		"insert into country (code, name) values ('FR', 'France');\n"+
+			"insert into country (code, name) values ('IT', 'Italy');\n",
+		content,
+	)
+}
+
+func TestArchiveMigrations(t *testing.T) {
+	files := map[string]string{
+		"2023-01-01/01_init.sql":        "create table country (name text);",
+		"2023-01-01/02_populate.sql.gz": string(gzipData(t, "insert into country values ('France');")),
+	}
+
+	var zipBuf bytes.Buffer
+
+	zw := zip.NewWriter(&zipBuf)
+
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zw.Close())
+
+	var tarBuf bytes.Buffer
+
+	gz := gzip.NewWriter(&tarBuf)
+	tw := tar.NewWriter(gz)
+
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./2023-01-01/", Typeflag: tar.TypeDir, Mode: 0o755}))
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     "./" + name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "migrations.zip"), zipBuf.Bytes(), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "migrations.tar.gz"), tarBuf.Bytes(), 0o600))
+
+	for _, archive := range []string{"migrations.zip", "migrations.tar.gz"} {
+		t.Run(archive, func(t *testing.T) {
+			migs, err := OpenArchiveMigrations(filepath.Join(dir, archive))
+			require.NoError(t, err)
+			assert.Equal(t, "archive "+filepath.Join(dir, archive), migs.String())
+
+			names, err := migs.List("sqlite")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"2023-01-01/01_init.sql", "2023-01-01/02_populate.sql"}, names)
+
+			content, err := migs.Content("2023-01-01/02_populate.sql")
+			require.NoError(t, err)
+			assert.Equal(t, "insert into country values ('France');", content)
+		})
+	}
+
+	_, err := OpenArchiveMigrations(filepath.Join(dir, "migrations.rar"))
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "migrations.rar"), nil, 0o600))
+	_, err = OpenArchiveMigrations(filepath.Join(dir, "migrations.rar"))
+	require.ErrorContains(t, err, "unsupported archive")
+
+	_, err = NewTarGzMigrations(bytes.NewReader(zipBuf.Bytes()))
+	require.ErrorContains(t, err, "failed to open tar.gz archive")
+}
+
+func TestCompositeMigrations_DuplicateVersion(t *testing.T) {
+	first := fstest.MapFS{
+		"01_a.sql": {Data: []byte("select 1;")},
+	}
+	second := fstest.MapFS{
+		"1_b.sql": {Data: []byte("select 2;")},
+	}
+
+	_, err := NewCompositeMigrations().
+		WithVersioning(NumericVersioning()).
+		WithFS("", first).
+		WithFS("", second).
+		List("sqlite")
+	require.ErrorContains(t, err, "duplicate version 1 for migrations 01_a.sql and 1_b.sql")
+
+	names, err := NewCompositeMigrations().
+		WithVersioning(NumericVersioning()).
+		WithFS("core", first).
+		WithFS("app", second).
+		List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"core/01_a.sql", "app/1_b.sql"}, names)
+}
+
+func TestFsMigrations_Unit_Dialects(t *testing.T) {
+	migs := NewFsMigrations(fstest.MapFS{
+		"01_unit/dejavu.manifest":            {},
+		"01_unit/01_a.sql":                   {Data: []byte("generic;")},
+		"01_unit.postgresql/dejavu.manifest": {},
+		"01_unit.postgresql/01_a.sql":        {Data: []byte("pg only;")},
+		"02_unit.mysql/dejavu.manifest":      {},
+		"02_unit.mysql/01_a.sql":             {Data: []byte("mysql only;")},
+	})
+
+	names, err := migs.List("postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_unit.postgresql"}, names)
+
+	names, err = migs.List("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01_unit", "02_unit.mysql"}, names)
+
+	mixed := NewFsMigrations(fstest.MapFS{
+		"03_u.sql":                        {Data: []byte("generic;")},
+		"03_u.postgresql/dejavu.manifest": {},
+		"03_u.postgresql/01_a.sql":        {Data: []byte("pg only;")},
+		"04_v/dejavu.manifest":            {},
+		"04_v/01_a.sql":                   {Data: []byte("generic;")},
+		"04_v.mysql.sql":                  {Data: []byte("mysql only;")},
+	})
+
+	names, err = mixed.List("postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"03_u.postgresql", "04_v"}, names)
+
+	names, err = mixed.List("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"03_u.sql", "04_v.mysql.sql"}, names)
+
+	tagged := NewFsMigrations(fstest.MapFS{
+		"01_unit/dejavu.manifest":     {},
+		"01_unit/01_a.mysql.sql":      {Data: []byte("mysql only;")},
+		"01_unit/01_a.postgresql.sql": {Data: []byte("pg only;")},
+	})
+
+	_, err = tagged.List("mysql")
+	require.ErrorContains(t, err, "dialect tagged file 01_a.mysql.sql in migration 01_unit")
+
+	_, err = tagged.Content("01_unit")
+	require.ErrorContains(t, err, "dialect tagged file 01_a.mysql.sql in migration 01_unit")
+
+	_, err = NewFsMigrations(fstest.MapFS{
+		"01_unit.postgres/dejavu.manifest": {},
+		"01_unit.postgres/01_a.sql":        {Data: []byte("pg only;")},
+	}).List("postgresql")
+	require.ErrorContains(t, err, "unknown dialect tag postgres in migration 01_unit.postgres")
+}
@@ -0,0 +1,154 @@
+package dejavu
+
+import (
+	"database/sql"
+	"io/fs"
+	"os"
+	"time"
+)
+
+type Option func(opts *options)
+
+type options struct {
+	cfg        *Config
+	dialect    *Dialect
+	includes   []string
+	stmts      DefaultStatements
+	versioning Versioning
+}
+
+func New(db *sql.DB, fsys fs.FS, opts ...Option) (DejaVu, error) {
+	o := options{cfg: NewConfig(nil, nil)}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.cfg.clock == nil {
+		o.cfg.clock = NewUtcClock()
+	}
+
+	if o.cfg.logger == nil {
+		o.cfg.logger = LogLogger{}
+	}
+
+	o.cfg.migs = NewFsMigrations(fsys).
+		WithIncludes(o.includes...).
+		WithLogger(o.cfg.logger).
+		WithVersioning(o.versioning)
+
+	if o.dialect == nil {
+		dialect, err := DetectDialect(db)
+		if err != nil {
+			return DejaVu{}, err
+		}
+
+		o.dialect = &dialect
+	}
+
+	o.cfg.db = o.dialect.NewDatabase(o.cfg.clock, o.cfg.logger, db, o.stmts)
+
+	return o.cfg.Build(), nil
+}
+
+func WithDialect(dialect Dialect) Option {
+	return func(opts *options) {
+		opts.dialect = &dialect
+	}
+}
+
+func WithIncludes(patterns ...string) Option {
+	return func(opts *options) {
+		opts.includes = patterns
+	}
+}
+
+func WithStatements(stmts DefaultStatements) Option {
+	return func(opts *options) {
+		opts.stmts = stmts
+	}
+}
+
+func WithVersioning(versioning Versioning) Option {
+	return func(opts *options) {
+		opts.versioning = versioning
+	}
+}
+
+func WithAliases(aliases map[string]string) Option {
+	return func(opts *options) {
+		opts.cfg.WithAliases(aliases)
+	}
+}
+
+func WithBackoff(backoff Backoff) Option {
+	return func(opts *options) {
+		opts.cfg.WithBackoff(backoff)
+	}
+}
+
+func WithClock(clock Clock) Option {
+	return func(opts *options) {
+		opts.cfg.WithClock(clock)
+	}
+}
+
+func WithData(data any) Option {
+	return func(opts *options) {
+		opts.cfg.WithData(data)
+	}
+}
+
+func WithHeartbeat(value time.Duration) Option {
+	return func(opts *options) {
+		opts.cfg.WithHeartbeat(value)
+	}
+}
+
+func WithLocker(locker Locker) Option {
+	return func(opts *options) {
+		opts.cfg.WithLocker(locker)
+	}
+}
+
+func WithLockTTL(value time.Duration) Option {
+	return func(opts *options) {
+		opts.cfg.WithLockTTL(value)
+	}
+}
+
+func WithLogger(logger Logger) Option {
+	return func(opts *options) {
+		opts.cfg.WithLogger(logger)
+	}
+}
+
+func WithNamespace(value string) Option {
+	return func(opts *options) {
+		opts.cfg.WithNamespace(value)
+	}
+}
+
+func WithSignals(signals ...os.Signal) Option {
+	return func(opts *options) {
+		opts.cfg.WithSignals(signals...)
+	}
+}
+
+func WithTick(value time.Duration) Option {
+	return func(opts *options) {
+		opts.cfg.WithTick(value)
+	}
+}
+
+func WithTimeout(value time.Duration) Option {
+	return func(opts *options) {
+		opts.cfg.WithTimeout(value)
+	}
+}
+
+func WithVersion(value string) Option {
+	return func(opts *options) {
+		opts.cfg.WithVersion(value)
+	}
+}
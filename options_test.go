@@ -0,0 +1,75 @@
+package dejavu
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	db, _ := sqlite(t)
+	logger := newTestLogger(t)
+
+	dv, err := New(db, newTestFS(t), WithLogger(logger), WithNamespace("billing"), WithVersion("1.2.3"))
+	require.NoError(t, err)
+
+	assert.Equal(t, DialectNameSQLite, dv.db.Name())
+	assert.Equal(t, "billing", dv.ns)
+	assert.Equal(t, logger, dv.logger)
+	assert.Equal(t, NewUtcClock(), dv.clock)
+
+	require.NoError(t, dv.Upgrade(context.Background()))
+
+	history, err := dv.History(context.Background())
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "1.2.3", history[0].Version)
+}
+
+func TestNew_Options(t *testing.T) {
+	db, _ := sqlite(t)
+	backoff := NewExponentialBackoff(time.Second, time.Minute)
+	clock := newTestClock()
+	locker := sqliteLocker(t)
+	logger := newTestLogger(t)
+
+	dv, err := New(db, newTestFS(t),
+		WithBackoff(backoff),
+		WithClock(clock),
+		WithData(map[string]string{"key": "value"}),
+		WithDialect(DialectSQLite()),
+		WithHeartbeat(time.Second),
+		WithLocker(locker),
+		WithLockTTL(time.Hour),
+		WithIncludes("*.sql", "*.ddl"),
+		WithLogger(logger),
+		WithSignals(os.Interrupt),
+		WithStatements(DefaultStatements{}.WithHistoryTable("billing_history")),
+		WithTick(time.Millisecond),
+		WithTimeout(time.Minute),
+		WithVersioning(DateFolderVersioning()),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, backoff, dv.backoff)
+	assert.Equal(t, clock, dv.clock)
+	assert.Equal(t, map[string]string{"key": "value"}, dv.data)
+	assert.Equal(t, time.Second, dv.heartbeat)
+	assert.Equal(t, locker, dv.locker)
+	assert.Equal(t, time.Hour, dv.ttl)
+	assert.Equal(t, []os.Signal{os.Interrupt}, dv.signals)
+	assert.Equal(t, time.Millisecond, dv.tick)
+	assert.Equal(t, time.Minute, dv.timeout)
+	assert.Equal(t, NewFsMigrations(newTestFS(t)).
+		WithIncludes("*.sql", "*.ddl").
+		WithLogger(logger).
+		WithVersioning(DateFolderVersioning()), dv.migs)
+
+	database, ok := dv.db.(DefaultDatabase)
+	require.True(t, ok)
+	assert.Equal(t, "billing_history", database.stmts.HistoryTable())
+}
@@ -0,0 +1,55 @@
+package dejavu
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Quotes struct {
+	Open  string
+	Close string
+}
+
+func (q Quotes) Quote(identifier string) string {
+	if q.Open == "" {
+		return identifier
+	}
+
+	return q.Open + strings.ReplaceAll(identifier, q.Close, q.Close+q.Close) + q.Close
+}
+
+func (q Quotes) String() string {
+	if q.Open == "" {
+		return "no quotes"
+	}
+
+	return fmt.Sprintf("quotes %s%s", q.Open, q.Close)
+}
+
+func QuotesMySQL() Quotes {
+	return Quotes{Open: "`", Close: "`"}
+}
+
+func QuotesOracle() Quotes {
+	return QuotesDouble()
+}
+
+func QuotesPostgreSQL() Quotes {
+	return QuotesDouble()
+}
+
+func QuotesSQLite() Quotes {
+	return QuotesDouble()
+}
+
+func QuotesSQLServer() Quotes {
+	return Quotes{Open: "[", Close: "]"}
+}
+
+func QuotesDouble() Quotes {
+	return Quotes{Open: `"`, Close: `"`}
+}
+
+func QuotesNone() Quotes {
+	return Quotes{}
+}
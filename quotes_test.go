@@ -0,0 +1,47 @@
+package dejavu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotes_Quote(t *testing.T) {
+	tests := []struct {
+		name       string
+		quotes     Quotes
+		identifier string
+		want       string
+	}{
+		{
+			name:       "none",
+			quotes:     QuotesNone(),
+			identifier: "deja_vu_history",
+			want:       "deja_vu_history",
+		},
+		{
+			name:       "double",
+			quotes:     QuotesPostgreSQL(),
+			identifier: "deja_vu_history",
+			want:       `"deja_vu_history"`,
+		},
+		{
+			name:       "backtick",
+			quotes:     QuotesMySQL(),
+			identifier: "deja_vu_history",
+			want:       "`deja_vu_history`",
+		},
+		{
+			name:       "escape",
+			quotes:     QuotesSQLite(),
+			identifier: `deja"vu`,
+			want:       `"deja""vu"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.quotes.Quote(tt.identifier))
+		})
+	}
+}
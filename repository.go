@@ -16,7 +16,9 @@ type Repository interface {
 	) error
 
 	Ping(ctx context.Context) error
+	Transactional() bool
 	Exec(ctx context.Context, stmt *Statement) error
+	ExecAffected(ctx context.Context, stmt *Statement) (int64, error)
 	Query(ctx context.Context, stmt *Statement) (*sql.Rows, error)
 	QueryRow(ctx context.Context, stmt *Statement) *sql.Row
 }
@@ -30,9 +32,16 @@ func NewRepository(db *sql.DB, logger Logger, placeholders Placeholders) DBRepos
 }
 
 type DBRepository struct {
-	db           *sql.DB
-	logger       Logger
-	placeholders Placeholders
+	db             *sql.DB
+	logger         Logger
+	noTransactions bool
+	placeholders   Placeholders
+}
+
+func (repo DBRepository) WithoutTransactions() DBRepository {
+	repo.noTransactions = true
+
+	return repo
 }
 
 func (repo DBRepository) EnsureTransaction(
@@ -40,6 +49,10 @@ func (repo DBRepository) EnsureTransaction(
 	opts *sql.TxOptions,
 	f func(ctx context.Context, repo Repository) error,
 ) error {
+	if repo.noTransactions {
+		return f(ctx, repo)
+	}
+
 	tx, err := repo.beginTx(ctx, opts)
 	if err != nil {
 		return err
@@ -76,6 +89,10 @@ func (repo DBRepository) Ping(ctx context.Context) error {
 	return repo.db.PingContext(ctx)
 }
 
+func (repo DBRepository) Transactional() bool {
+	return !repo.noTransactions
+}
+
 func (repo DBRepository) Exec(ctx context.Context, stmt *Statement) error {
 	query, args := stmt.WithPlaceholders(repo.placeholders)
 	LogStatement(repo.logger, query, args)
@@ -84,6 +101,18 @@ func (repo DBRepository) Exec(ctx context.Context, stmt *Statement) error {
 	return err
 }
 
+func (repo DBRepository) ExecAffected(ctx context.Context, stmt *Statement) (int64, error) {
+	query, args := stmt.WithPlaceholders(repo.placeholders)
+	LogStatement(repo.logger, query, args)
+
+	res, err := repo.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
 func (repo DBRepository) Query(ctx context.Context, stmt *Statement) (*sql.Rows, error) {
 	query, args := stmt.WithPlaceholders(repo.placeholders)
 	LogStatement(repo.logger, query, args)
@@ -99,6 +128,10 @@ func (repo DBRepository) QueryRow(ctx context.Context, stmt *Statement) *sql.Row
 }
 
 func (repo DBRepository) String() string {
+	if repo.noTransactions {
+		return fmt.Sprintf("SQL db without transactions with %v", repo.placeholders)
+	}
+
 	return fmt.Sprintf("SQL db with %v", repo.placeholders)
 }
 
@@ -144,6 +177,18 @@ func (repo txRepository) Exec(ctx context.Context, stmt *Statement) error {
 	return err
 }
 
+func (repo txRepository) ExecAffected(ctx context.Context, stmt *Statement) (int64, error) {
+	query, args := stmt.WithPlaceholders(repo.placeholders)
+	LogStatement(repo.logger, query, args)
+
+	res, err := repo.tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
 func (repo txRepository) Query(ctx context.Context, stmt *Statement) (*sql.Rows, error) {
 	query, args := stmt.WithPlaceholders(repo.placeholders)
 	LogStatement(repo.logger, query, args)
@@ -0,0 +1,218 @@
+package dejavu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Concurrency = 4
+)
+
+type TenantStatus string
+
+const (
+	TenantFailed    TenantStatus = "failed"
+	TenantSkipped   TenantStatus = "skipped"
+	TenantSucceeded TenantStatus = "succeeded"
+)
+
+type Tenant struct {
+	Name     string
+	Database Database
+	Data     any
+}
+
+type TenantResult struct {
+	Tenant   string
+	Status   TenantStatus
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+func (r TenantResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("Tenant %s %s after %v: %v", r.Tenant, r.Status, r.Duration, r.Err)
+	}
+
+	return fmt.Sprintf("Tenant %s %s after %v", r.Tenant, r.Status, r.Duration)
+}
+
+type Report []TenantResult
+
+func (r Report) Failed() Report {
+	return r.filter(TenantFailed)
+}
+
+func (r Report) Skipped() Report {
+	return r.filter(TenantSkipped)
+}
+
+func (r Report) Succeeded() Report {
+	return r.filter(TenantSucceeded)
+}
+
+func (r Report) Err() error {
+	errs := make([]error, 0, len(r))
+
+	for _, res := range r.Failed() {
+		errs = append(errs, newError(res.Err, "tenant %s failed", res.Tenant))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r Report) String() string {
+	sb := strings.Builder{}
+
+	sb.WriteString(fmt.Sprintf("%d tenant(s): %d succeeded, %d failed, %d skipped",
+		len(r),
+		len(r.Succeeded()),
+		len(r.Failed()),
+		len(r.Skipped()),
+	))
+
+	for _, res := range r {
+		sb.WriteRune('\n')
+		sb.WriteString(res.String())
+	}
+
+	return sb.String()
+}
+
+func (r Report) filter(status TenantStatus) Report {
+	result := make(Report, 0, len(r))
+
+	for _, res := range r {
+		if res.Status == status {
+			result = append(result, res)
+		}
+	}
+
+	return result
+}
+
+type Runner struct {
+	cfg             Config
+	concurrency     int
+	continueOnError bool
+}
+
+func NewRunner(cfg *Config) *Runner {
+	return &Runner{
+		cfg:         *cfg,
+		concurrency: Concurrency,
+	}
+}
+
+func (r *Runner) WithConcurrency(value int) *Runner {
+	r.concurrency = value
+
+	return r
+}
+
+func (r *Runner) WithContinueOnError(value bool) *Runner {
+	r.continueOnError = value
+
+	return r
+}
+
+func (r *Runner) Upgrade(ctx context.Context, tenants []Tenant) (Report, error) {
+	report := make(Report, len(tenants))
+
+	for i, tenant := range tenants {
+		report[i] = TenantResult{Tenant: tenant.Name, Status: TenantSkipped}
+	}
+
+	if err := r.init(ctx, tenants); err != nil {
+		return report, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, max(r.concurrency, 1))
+	wg := sync.WaitGroup{}
+
+	for i, tenant := range tenants {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			wg.Add(1)
+
+			go func(i int, tenant Tenant) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				report[i] = r.upgrade(ctx, tenant)
+
+				if report[i].Err != nil && !r.continueOnError {
+					cancel()
+				}
+			}(i, tenant)
+		}
+	}
+
+	wg.Wait()
+
+	return report, report.Err()
+}
+
+func (r *Runner) init(ctx context.Context, tenants []Tenant) error {
+	for _, tenant := range tenants {
+		if tenant.Database == nil {
+			return r.cfg.Build().Init(ctx)
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) upgrade(ctx context.Context, tenant Tenant) TenantResult {
+	cfg := r.config(tenant)
+	dv := cfg.Build()
+	result := TenantResult{
+		Tenant: tenant.Name,
+		Status: TenantSucceeded,
+		Start:  dv.clock.Now(),
+	}
+
+	dv.logger.Log(fmt.Sprintf("Upgrading tenant %s...", tenant.Name))
+
+	if err := dv.Upgrade(ctx); err != nil {
+		result.Status = TenantFailed
+		result.Err = err
+	}
+
+	result.Duration = dv.clock.Now().Sub(result.Start)
+
+	dv.logger.Log(result.String())
+
+	return result
+}
+
+func (r *Runner) config(tenant Tenant) Config {
+	cfg := r.cfg
+
+	if tenant.Database == nil {
+		cfg.ns = tenant.Name
+	} else {
+		cfg.db = tenant.Database
+		cfg.locker = nil
+	}
+
+	cfg.data = tenant.Data
+
+	return cfg
+}
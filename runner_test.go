@@ -0,0 +1,128 @@
+package dejavu
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTenantMigrations() FsMigrations {
+	return FsMigrations{fs: fstest.MapFS{
+		"01_create_table.sql": {Data: []byte("create table {{.Prefix}}_country (name text not null);")},
+		"02_insert.sql":       {Data: []byte("insert into {{.Prefix}}_country values ('France');")},
+	}}
+}
+
+func newTestTenantDatabase(t *testing.T) DefaultDatabase {
+	t.Helper()
+
+	db, syntax := sqlite(t)
+	logger := newTestLogger(t)
+
+	return NewDatabase(newTestClock(), logger, "sqlite", NewRepository(db, logger, syntax), DefaultStatements{})
+}
+
+func TestRunner_Upgrade_SharedDatabase(t *testing.T) {
+	db, syntax := sqlite(t)
+	cfg := newTestConfig(t, db, "sqlite", syntax)
+	cfg.migs = newTestTenantMigrations()
+	ctx := context.Background()
+
+	report, err := NewRunner(cfg).WithConcurrency(1).Upgrade(ctx, []Tenant{
+		{Name: "tenant1", Data: map[string]string{"Prefix": "tenant1"}},
+		{Name: "tenant2", Data: map[string]string{"Prefix": "tenant2"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	assert.Len(t, report.Succeeded(), 2)
+
+	database, ok := cfg.db.(DefaultDatabase)
+	require.True(t, ok)
+
+	for _, table := range []string{"tenant1_country", "tenant2_country"} {
+		count, err := database.Count(ctx, table)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+
+	history, err := database.History(ctx, "tenant2")
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestRunner_Upgrade_ContinueOnError(t *testing.T) {
+	cfg := newTestConfig(t, nil, "sqlite", PlaceholdersSQLite())
+	cfg.migs = newTestTenantMigrations()
+	tenants := []Tenant{
+		{Name: "tenant1", Database: newTestTenantDatabase(t), Data: map[string]string{"Prefix": "tenant1"}},
+		{Name: "tenant2", Database: newTestTenantDatabase(t), Data: map[string]string{"Prefix": "bad prefix"}},
+		{Name: "tenant3", Database: newTestTenantDatabase(t), Data: map[string]string{"Prefix": "tenant3"}},
+	}
+
+	report, err := NewRunner(cfg).WithConcurrency(2).WithContinueOnError(true).Upgrade(context.Background(), tenants)
+	require.Error(t, err)
+	require.Len(t, report, 3)
+	assert.Len(t, report.Succeeded(), 2)
+	require.Len(t, report.Failed(), 1)
+	assert.Equal(t, "tenant2", report.Failed()[0].Tenant)
+	assert.Contains(t, report.String(), "3 tenant(s): 2 succeeded, 1 failed, 0 skipped")
+}
+
+func TestRunner_Upgrade_StopOnError(t *testing.T) {
+	cfg := newTestConfig(t, nil, "sqlite", PlaceholdersSQLite())
+	cfg.migs = newTestTenantMigrations()
+	tenants := []Tenant{
+		{Name: "tenant1", Database: newTestTenantDatabase(t), Data: map[string]string{"Prefix": "bad prefix"}},
+		{Name: "tenant2", Database: newTestTenantDatabase(t), Data: map[string]string{"Prefix": "tenant2"}},
+	}
+
+	report, err := NewRunner(cfg).WithConcurrency(1).Upgrade(context.Background(), tenants)
+	require.Error(t, err)
+	require.Len(t, report, 2)
+	assert.Equal(t, TenantFailed, report[0].Status)
+	assert.Equal(t, TenantSkipped, report[1].Status)
+}
+
+func TestRunner_Upgrade_Twice(t *testing.T) {
+	db, syntax := sqlite(t)
+	cfg := newTestConfig(t, db, "sqlite", syntax)
+	cfg.migs = newTestTenantMigrations()
+	ctx := context.Background()
+	tenants := []Tenant{
+		{Name: "tenant1", Data: map[string]string{"Prefix": "tenant1"}},
+		{Name: "tenant2", Database: newTestTenantDatabase(t), Data: map[string]string{"Prefix": "tenant2"}},
+	}
+
+	for i := 0; i < 2; i++ {
+		report, err := NewRunner(cfg).Upgrade(ctx, tenants)
+		require.NoError(t, err)
+		assert.Len(t, report.Succeeded(), 2)
+	}
+
+	history, err := tenants[1].Database.History(ctx, DefaultNamespace)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestRunner_config(t *testing.T) {
+	db, syntax := sqlite(t)
+	locker := sqliteLocker(t)
+	cfg := newTestConfig(t, db, "sqlite", syntax).WithLocker(locker)
+	runner := NewRunner(cfg)
+
+	shared := runner.config(Tenant{Name: "tenant1"})
+	assert.Equal(t, "tenant1", shared.ns)
+	assert.Equal(t, locker, shared.locker)
+
+	database := newTestTenantDatabase(t)
+	own := runner.config(Tenant{Name: "tenant2", Database: database})
+	assert.Equal(t, DefaultNamespace, own.ns)
+	assert.Equal(t, database, own.db)
+	assert.Nil(t, own.locker)
+
+	dv := own.Build()
+	assert.Equal(t, database, dv.locker)
+}
@@ -3,11 +3,14 @@ package dejavu
 import (
 	"database/sql"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 )
 
+var dollarQuoteTag = regexp.MustCompile(`^\$[A-Za-z_0-9]*\$`)
+
 type PlaceholderSyntax string
 
 const (
@@ -41,6 +44,10 @@ func PlaceholdersSQLite() Placeholders {
 	return PlaceholdersQuestionMark()
 }
 
+func PlaceholdersSQLServer() Placeholders {
+	return PlaceholdersIndexed("@p")
+}
+
 func PlaceholdersIndexed(prefix string) Placeholders {
 	return Placeholders{
 		Prefix: prefix,
@@ -63,8 +70,9 @@ func PlaceholdersQuestionMark() Placeholders {
 }
 
 type Statement struct {
-	sql  string
-	args []sql.NamedArg
+	sql    string
+	args   []sql.NamedArg
+	column string
 }
 
 func NewStatement(format string, args ...any) *Statement {
@@ -95,7 +103,7 @@ func (s *Statement) WithIndexedArgs(prefix string) (string, []any) {
 	stmt := s.sql
 
 	for i, arg := range s.args {
-		stmt = strings.ReplaceAll(stmt, ":"+arg.Name, prefix+strconv.Itoa(i+1))
+		stmt = replaceArg(stmt, arg.Name, prefix+strconv.Itoa(i+1))
 		args[i] = arg.Value
 	}
 
@@ -122,14 +130,14 @@ func (s *Statement) WithQuestionMarkArgs() (string, []any) {
 	stmt := s.sql
 
 	for _, arg := range s.args {
-		for _, idx := range allIndexes(s.sql, ":"+arg.Name) {
+		for _, idx := range namedArgIndexes(s.sql, arg.Name) {
 			argIndexes = append(argIndexes, argIndex{
 				idx:   idx,
 				value: arg.Value,
 			})
 		}
 
-		stmt = strings.ReplaceAll(stmt, ":"+arg.Name, "?")
+		stmt = replaceArg(stmt, arg.Name, "?")
 	}
 
 	sort.Slice(argIndexes, func(i, j int) bool {
@@ -175,3 +183,128 @@ func allIndexes(s, substr string) []int {
 
 	return result
 }
+
+func namedArgIndexes(s, name string) []int {
+	result := make([]int, 0, 1)
+
+	for _, idx := range allIndexes(s, ":"+name) {
+		end := idx + len(name) + 1
+
+		if end < len(s) && isIdentifierChar(s[end]) {
+			continue
+		}
+
+		result = append(result, idx)
+	}
+
+	return result
+}
+
+func replaceArg(s, name, replacement string) string {
+	sb := strings.Builder{}
+	start := 0
+
+	for _, idx := range namedArgIndexes(s, name) {
+		sb.WriteString(s[start:idx])
+		sb.WriteString(replacement)
+		start = idx + len(name) + 1
+	}
+
+	sb.WriteString(s[start:])
+
+	return sb.String()
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func newStatements(sqls []string) []*Statement {
+	result := make([]*Statement, 0, len(sqls))
+
+	for _, s := range sqls {
+		result = append(result, NewStatement("%s", s))
+	}
+
+	return result
+}
+
+func splitStatements(script string, backslashEscapes bool) []string {
+	result := make([]string, 0)
+	start := 0
+
+	appendStatement := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); stmt != "" {
+			result = append(result, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i+1, c, backslashEscapes)
+		case strings.HasPrefix(script[i:], "--"):
+			i = skipUntil(script, i+2, "\n")
+		case strings.HasPrefix(script[i:], "/*"):
+			i = skipUntil(script, i+2, "*/")
+		case c == '$':
+			if tag := dollarQuoteTag.FindString(script[i:]); tag != "" {
+				i = skipUntil(script, i+len(tag), tag)
+			}
+		case c == ';':
+			appendStatement(i)
+			start = i + 1
+		}
+	}
+
+	appendStatement(len(script))
+
+	return result
+}
+
+func skipQuoted(s string, from int, quote byte, backslashEscapes bool) int {
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+func skipUntil(s string, from int, end string) int {
+	idx := strings.Index(s[from:], end)
+	if idx == -1 {
+		return len(s)
+	}
+
+	return from + idx + len(end) - 1
+}
+
+func trimLeadingComments(stmt string) string {
+	for {
+		stmt = strings.TrimSpace(stmt)
+
+		var end int
+
+		switch {
+		case strings.HasPrefix(stmt, "--"):
+			end = skipUntil(stmt, 2, "\n") + 1
+		case strings.HasPrefix(stmt, "/*"):
+			end = skipUntil(stmt, 2, "*/") + 1
+		default:
+			return stmt
+		}
+
+		if end > len(stmt) {
+			return ""
+		}
+
+		stmt = stmt[end:]
+	}
+}
@@ -101,6 +101,38 @@ func TestStatement_WithSyntax(t *testing.T) {
 	}
 }
 
+func TestStatement_WithSyntax_Prefix(t *testing.T) {
+	tests := []struct {
+		name         string
+		placeholders Placeholders
+		want         string
+		want1        []any
+	}{
+		{
+			name:         "index",
+			placeholders: PlaceholdersIndexed("$"),
+			want:         "select * from test_table where name = $1 and namespace = $2",
+			want1:        []any{"value1", "value2"},
+		},
+		{
+			name:         "question mark",
+			placeholders: PlaceholdersQuestionMark(),
+			want:         "select * from test_table where name = ? and namespace = ?",
+			want1:        []any{"value1", "value2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := NewStatement("select * from test_table where name = :name and namespace = :namespace").
+				Arg("name", "value1").
+				Arg("namespace", "value2").
+				WithPlaceholders(tt.placeholders)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want1, got1)
+		})
+	}
+}
+
 func Test_allIndexes(t *testing.T) {
 	type args struct {
 		s      string
@@ -144,3 +176,63 @@ func Test_allIndexes(t *testing.T) {
 		})
 	}
 }
+
+func Test_splitStatements(t *testing.T) {
+	tests := []struct {
+		name             string
+		script           string
+		backslashEscapes bool
+		want             []string
+	}{
+		{
+			name:   "empty",
+			script: " \n ",
+			want:   []string{},
+		},
+		{
+			name:   "single",
+			script: "create table country (name text)",
+			want:   []string{"create table country (name text)"},
+		},
+		{
+			name:   "multiple",
+			script: "create table country (name text);\ninsert into country values ('France');\n",
+			want:   []string{"create table country (name text)", "insert into country values ('France')"},
+		},
+		{
+			name:   "quotes",
+			script: `insert into country values ('a;b', "c;d", ` + "`e;f`" + `, 'it''s;');select 1`,
+			want:   []string{`insert into country values ('a;b', "c;d", ` + "`e;f`" + `, 'it''s;')`, "select 1"},
+		},
+		{
+			name:   "backslash",
+			script: `select 'C:\'; select 1`,
+			want:   []string{`select 'C:\'`, "select 1"},
+		},
+		{
+			name:             "backslash escapes",
+			script:           `insert into t values ('it\'s; fine'); select 1`,
+			backslashEscapes: true,
+			want:             []string{`insert into t values ('it\'s; fine')`, "select 1"},
+		},
+		{
+			name:   "comments",
+			script: "-- first; statement\nselect 1; /* second;\nstatement */ select 2;",
+			want:   []string{"-- first; statement\nselect 1", "/* second;\nstatement */ select 2"},
+		},
+		{
+			name:   "dollar quotes",
+			script: "create function f() returns int as $body$ begin return 1; end; $body$ language plpgsql;select 1",
+			want: []string{
+				"create function f() returns int as $body$ begin return 1; end; $body$ language plpgsql",
+				"select 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitStatements(tt.script, tt.backslashEscapes))
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package dejavu
 
-import "fmt"
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
 
 const (
 	HistoryTableName       = "deja_vu_history"
@@ -8,6 +13,30 @@ const (
 	HistoryColumnStartedAt = "started_at"
 	HistoryColumnDuration  = "duration_ms"
 	HistoryColumnChecksum  = "checksum"
+	HistoryColumnStatus    = "status"
+	HistoryColumnError     = "error_message"
+	HistoryColumnRank      = "installed_rank"
+	HistoryColumnDesc      = "description"
+	HistoryColumnHostname  = "hostname"
+	HistoryColumnPid       = "pid"
+	HistoryColumnOsUser    = "os_user"
+	HistoryColumnDBUser    = "db_user"
+	HistoryColumnVersion   = "version"
+	HistoryColumnNamespace = "namespace"
+	HistoryColumnProgress  = "progress"
+)
+
+const (
+	HistoryErrorMaxLength = 1024
+)
+
+const (
+	MetadataTableName     = "deja_vu_metadata"
+	MetadataColumnVersion = "version"
+)
+
+const (
+	MetadataVersion = 3
 )
 
 const (
@@ -16,116 +45,627 @@ const (
 	LockColumnHostname = "hostname"
 	LockColumnPid      = "pid"
 	LockColumnSince    = "since"
+	LockColumnToken    = "token"
 )
 
 type Statements interface {
 	fmt.Stringer
 
+	ReadOnlyTransactions() bool
+
+	HistoryTable() string
+	LockTable() string
+	MetadataTable() string
+
 	CountFromTable(name string) *Statement
+	TableExists(table string) *Statement
+	ProbeColumn(table, column string) *Statement
 
 	CreateHistoryTable() *Statement
 	CreateLockTable() *Statement
+	CreateMetadataTable() *Statement
+	UpgradeLockTable() []*Statement
+
+	MetadataVersion() *Statement
+	InsertMetadataVersion(version int) *Statement
+	UpdateMetadataVersion(version int) *Statement
+	UpgradeMetadata(from int) []*Statement
+	HistoryBackupTable() string
+	RestoreHistory(dropHistory bool) []*Statement
+
+	UnrankedHistory() *Statement
+	BackfillHistory(mig Migration) *Statement
+
+	CurrentTimestamp() *Statement
 
 	Lock(lck Lock) *Statement
+	ReadLock(lck Lock) *Statement
+	RefreshLock(lck Lock) *Statement
+	ExpireLock(lck Lock, expiry time.Time) *Statement
 	Unlock(lck Lock) *Statement
+	ForceUnlock(lck Lock) *Statement
+
+	CurrentUser() *Statement
+	MaxInstalledRank() *Statement
 
-	History() *Statement
+	History(namespace string) *Statement
+	Script(content string) []*Statement
+	SplitScript(content string) []*Statement
 	Log(mig Migration) *Statement
+	LogFailure(mig Migration) *Statement
+	UpdateProgress(mig Migration) *Statement
+	DeleteFailure(mig Migration) *Statement
+	RenameMigration(mig Migration, name string) *Statement
+}
+
+type DefaultStatements struct {
+	dialect  string
+	quotes   Quotes
+	schema   string
+	history  string
+	lock     string
+	metadata string
+	types    columnTypes
+}
+
+type columnTypes struct {
+	integer   string
+	timestamp string
+	varchar   string
+}
+
+func (s DefaultStatements) WithQuotes(quotes Quotes) DefaultStatements {
+	s.quotes = quotes
+
+	return s
 }
 
-type DefaultStatements struct{}
+func (s DefaultStatements) WithSchema(schema string) DefaultStatements {
+	s.schema = schema
+
+	return s
+}
+
+func (s DefaultStatements) WithHistoryTable(name string) DefaultStatements {
+	s.history = name
+
+	return s
+}
+
+func (s DefaultStatements) WithLockTable(name string) DefaultStatements {
+	s.lock = name
+
+	return s
+}
+
+func (s DefaultStatements) WithMetadataTable(name string) DefaultStatements {
+	s.metadata = name
+
+	return s
+}
+
+func (s DefaultStatements) ReadOnlyTransactions() bool {
+	return true
+}
+
+func (s DefaultStatements) HistoryTable() string {
+	return s.historyTable()
+}
+
+func (s DefaultStatements) LockTable() string {
+	return s.lockTable()
+}
+
+func (s DefaultStatements) MetadataTable() string {
+	return s.metadataTable()
+}
 
 func (s DefaultStatements) CountFromTable(name string) *Statement {
 	return NewStatement("select count(1) from %s", name)
 }
 
+func (s DefaultStatements) TableExists(string) *Statement {
+	return nil
+}
+
+func (s DefaultStatements) ProbeColumn(table, column string) *Statement {
+	return NewStatement("select %s from %s where 1 = 0", column, s.qualify(table))
+}
+
 func (s DefaultStatements) CreateHistoryTable() *Statement {
+	return s.createHistoryTable(MetadataVersion)
+}
+
+func (s DefaultStatements) CreateLockTable() *Statement {
 	return NewStatement(
 		`create table %s (
-			%s varchar(512) not null,
-			%s timestamp    not null,
-			%s int          not null,
-			%s char(43)     not null,
-			constraint %s_pk primary key (name)
+			%s %s not null,
+			%s %s not null,
+			%s %s not null,
+			%s %s not null,
+			%s %s,
+			constraint %s primary key (%s)
 		)`,
-		HistoryTableName,
-		HistoryColumnName,
-		HistoryColumnStartedAt,
-		HistoryColumnDuration,
-		HistoryColumnChecksum,
-		HistoryTableName,
+		s.qualifiedLockTable(),
+		LockColumnID, s.integerType(),
+		LockColumnHostname, s.varcharType(128),
+		LockColumnPid, s.integerType(),
+		LockColumnSince, s.timestampType(),
+		LockColumnToken, s.varcharType(64),
+		s.quotes.Quote(s.lockTable()+"_pk"),
+		LockColumnID,
 	)
 }
 
-func (s DefaultStatements) CreateLockTable() *Statement {
+func (s DefaultStatements) UpgradeLockTable() []*Statement {
+	return []*Statement{
+		NewStatement("alter table %s add %s %s", s.qualifiedLockTable(), LockColumnToken, s.varcharType(64)),
+	}
+}
+
+func (s DefaultStatements) CreateMetadataTable() *Statement {
 	return NewStatement(
 		`create table %s (
-			%s int          not null,
-			%s varchar(128) not null,
-			%s int          not null,
-			%s timestamp    not null,
-			constraint %s_pk primary key (id)
+			%s %s not null
 		)`,
-		LockTableName,
-		LockColumnID,
-		LockColumnHostname,
-		LockColumnPid,
-		LockColumnSince,
-		LockTableName,
+		s.qualifiedMetadataTable(),
+		MetadataColumnVersion,
+		s.integerType(),
+	)
+}
+
+func (s DefaultStatements) MetadataVersion() *Statement {
+	return NewStatement("select max(%s) from %s", MetadataColumnVersion, s.qualifiedMetadataTable())
+}
+
+func (s DefaultStatements) InsertMetadataVersion(version int) *Statement {
+	return NewStatement(
+		"insert into %s (%s) values (:version)",
+		s.qualifiedMetadataTable(),
+		MetadataColumnVersion,
+	).
+		Arg("version", version)
+}
+
+func (s DefaultStatements) UpdateMetadataVersion(version int) *Statement {
+	return NewStatement(
+		"update %s set %s = :version",
+		s.qualifiedMetadataTable(),
+		MetadataColumnVersion,
+	).
+		Arg("version", version)
+}
+
+func (s DefaultStatements) UpgradeMetadata(from int) []*Statement {
+	switch from {
+	case 0:
+		return []*Statement{
+			s.addHistoryColumn(HistoryColumnStatus, s.varcharType(16)),
+			s.addHistoryColumn(HistoryColumnError, s.varcharType(HistoryErrorMaxLength)),
+			s.addHistoryColumn(HistoryColumnRank, s.integerType()),
+			s.addHistoryColumn(HistoryColumnDesc, s.varcharType(512)),
+			s.addHistoryColumn(HistoryColumnHostname, s.varcharType(128)),
+			s.addHistoryColumn(HistoryColumnPid, s.integerType()),
+			s.addHistoryColumn(HistoryColumnOsUser, s.varcharType(128)),
+			s.addHistoryColumn(HistoryColumnDBUser, s.varcharType(128)),
+			s.addHistoryColumn(HistoryColumnVersion, s.varcharType(128)),
+		}
+	case 1:
+		return s.rebuildHistory(NewStatement(
+			"create table %s as select * from %s",
+			s.qualify(s.HistoryBackupTable()),
+			s.qualifiedHistoryTable(),
+		))
+	case 2:
+		return []*Statement{
+			s.addHistoryColumn(HistoryColumnProgress, s.integerType()),
+		}
+	}
+
+	return nil
+}
+
+func (s DefaultStatements) UnrankedHistory() *Statement {
+	return NewStatement(
+		"select %s from %s where %s is null order by %s",
+		HistoryColumnName,
+		s.qualifiedHistoryTable(),
+		HistoryColumnRank,
+		HistoryColumnName,
 	)
 }
 
+func (s DefaultStatements) BackfillHistory(mig Migration) *Statement {
+	return NewStatement(
+		"update %s set %s = :status, %s = :installed_rank, %s = :description where %s = :name",
+		s.qualifiedHistoryTable(),
+		HistoryColumnStatus,
+		HistoryColumnRank,
+		HistoryColumnDesc,
+		HistoryColumnName,
+	).
+		Arg("status", string(MigrationSucceeded)).
+		Arg("installed_rank", mig.InstalledRank).
+		Arg("description", nullString(mig.Description)).
+		Arg("name", mig.Name)
+}
+
+func (s DefaultStatements) CurrentTimestamp() *Statement {
+	return NewStatement("select current_timestamp")
+}
+
 func (s DefaultStatements) Lock(lck Lock) *Statement {
 	return NewStatement(
-		"insert into %s (%s, %s, %s, %s) values (:id, :hostname, :pid, :since)",
-		LockTableName,
+		"insert into %s (%s, %s, %s, %s, %s) values (:id, :hostname, :pid, current_timestamp, :token)",
+		s.qualifiedLockTable(),
 		LockColumnID,
 		LockColumnHostname,
 		LockColumnPid,
 		LockColumnSince,
+		LockColumnToken,
 	).
 		Arg("id", lck.id).
 		Arg("hostname", lck.hostname).
 		Arg("pid", lck.pid).
-		Arg("since", lck.since)
+		Arg("token", lck.token)
+}
+
+func (s DefaultStatements) ReadLock(lck Lock) *Statement {
+	return NewStatement(
+		"select %s, %s, %s from %s where %s = :id",
+		LockColumnHostname,
+		LockColumnPid,
+		LockColumnSince,
+		s.qualifiedLockTable(),
+		LockColumnID,
+	).
+		Arg("id", lck.id)
+}
+
+func (s DefaultStatements) RefreshLock(lck Lock) *Statement {
+	return NewStatement(
+		"update %s set %s = current_timestamp where %s = :id and %s = :token",
+		s.qualifiedLockTable(),
+		LockColumnSince,
+		LockColumnID,
+		LockColumnToken,
+	).
+		Arg("id", lck.id).
+		Arg("token", lck.token)
+}
+
+func (s DefaultStatements) ExpireLock(lck Lock, expiry time.Time) *Statement {
+	return NewStatement(
+		"delete from %s where %s = :id and %s < :expiry",
+		s.qualifiedLockTable(),
+		LockColumnID,
+		LockColumnSince,
+	).
+		Arg("id", lck.id).
+		Arg("expiry", expiry)
 }
 
 func (s DefaultStatements) Unlock(lck Lock) *Statement {
+	return NewStatement(
+		"delete from %s where %s = :id and %s = :token",
+		s.qualifiedLockTable(),
+		LockColumnID,
+		LockColumnToken,
+	).
+		Arg("id", lck.id).
+		Arg("token", lck.token)
+}
+
+func (s DefaultStatements) ForceUnlock(lck Lock) *Statement {
 	return NewStatement(
 		"delete from %s where %s = :id",
-		LockTableName,
+		s.qualifiedLockTable(),
 		LockColumnID,
 	).
 		Arg("id", lck.id)
 }
 
-func (s DefaultStatements) History() *Statement {
+func (s DefaultStatements) CurrentUser() *Statement {
+	return NewStatement("select current_user")
+}
+
+func (s DefaultStatements) MaxInstalledRank() *Statement {
 	return NewStatement(
-		"select %s, %s, %s, %s from %s order by %s",
-		HistoryColumnName,
-		HistoryColumnStartedAt,
-		HistoryColumnDuration,
-		HistoryColumnChecksum,
-		HistoryTableName,
-		HistoryColumnName,
+		"select coalesce(max(%s), 0) from %s",
+		HistoryColumnRank,
+		s.qualifiedHistoryTable(),
 	)
 }
 
+func (s DefaultStatements) History(namespace string) *Statement {
+	return NewStatement(
+		"select %s, %s from %s where %s = :namespace order by %s",
+		strings.Join(historyColumnsV1(), ", "),
+		HistoryColumnProgress,
+		s.qualifiedHistoryTable(),
+		HistoryColumnNamespace,
+		HistoryColumnRank,
+	).
+		Arg("namespace", namespace)
+}
+
+func (s DefaultStatements) Script(content string) []*Statement {
+	return []*Statement{NewStatement("%s", content)}
+}
+
+func (s DefaultStatements) SplitScript(content string) []*Statement {
+	return newStatements(splitStatements(content, false))
+}
+
 func (s DefaultStatements) Log(mig Migration) *Statement {
+	return s.insertHistory(mig, MigrationSucceeded)
+}
+
+func (s DefaultStatements) LogFailure(mig Migration) *Statement {
+	return s.insertHistory(mig, MigrationFailed)
+}
+
+func (s DefaultStatements) UpdateProgress(mig Migration) *Statement {
 	return NewStatement(
-		"insert into %s (%s, %s, %s, %s) values (:name, :start, :duration_ms, :checksum)",
-		HistoryTableName,
-		HistoryColumnName,
-		HistoryColumnStartedAt,
+		`update %s set %s = :status, %s = :duration_ms, %s = :checksum, %s = :error, %s = :progress
+		where %s = :namespace and %s = :name`,
+		s.qualifiedHistoryTable(),
+		HistoryColumnStatus,
 		HistoryColumnDuration,
 		HistoryColumnChecksum,
+		HistoryColumnError,
+		HistoryColumnProgress,
+		HistoryColumnNamespace,
+		HistoryColumnName,
+	).
+		Arg("status", string(mig.Status)).
+		Arg("duration_ms", mig.DurationMs).
+		Arg("checksum", mig.Checksum).
+		Arg("error", nullString(truncate(mig.Error, HistoryErrorMaxLength))).
+		Arg("progress", mig.Progress).
+		Arg("namespace", mig.Namespace).
+		Arg("name", mig.Name)
+}
+
+func (s DefaultStatements) DeleteFailure(mig Migration) *Statement {
+	return NewStatement(
+		"delete from %s where %s = :namespace and %s = :name and %s = :status",
+		s.qualifiedHistoryTable(),
+		HistoryColumnNamespace,
+		HistoryColumnName,
+		HistoryColumnStatus,
+	).
+		Arg("namespace", mig.Namespace).
+		Arg("name", mig.Name).
+		Arg("status", string(MigrationFailed))
+}
+
+func (s DefaultStatements) RenameMigration(mig Migration, name string) *Statement {
+	return NewStatement(
+		"update %s set %s = :new_name where %s = :namespace and %s = :name",
+		s.qualifiedHistoryTable(),
+		HistoryColumnName,
+		HistoryColumnNamespace,
+		HistoryColumnName,
+	).
+		Arg("new_name", name).
+		Arg("namespace", mig.Namespace).
+		Arg("name", mig.Name)
+}
+
+func (s DefaultStatements) insertHistory(mig Migration, status MigrationStatus) *Statement {
+	return NewStatement(
+		`insert into %s (%s, %s, %s)
+		values (:name, :start, :duration_ms, :checksum, :status, :error, :installed_rank,
+			:description, :hostname, :pid, :os_user, :db_user, :version, :namespace, :progress)`,
+		s.qualifiedHistoryTable(),
+		strings.Join(historyColumnsV1(), ", "),
+		HistoryColumnNamespace,
+		HistoryColumnProgress,
 	).
 		Arg("name", mig.Name).
 		Arg("start", mig.Start).
 		Arg("duration_ms", mig.DurationMs).
-		Arg("checksum", mig.Checksum)
+		Arg("checksum", mig.Checksum).
+		Arg("status", string(status)).
+		Arg("error", nullString(truncate(mig.Error, HistoryErrorMaxLength))).
+		Arg("installed_rank", mig.InstalledRank).
+		Arg("description", nullString(mig.Description)).
+		Arg("hostname", nullString(mig.Hostname)).
+		Arg("pid", mig.Pid).
+		Arg("os_user", nullString(mig.OsUser)).
+		Arg("db_user", nullString(mig.DBUser)).
+		Arg("version", nullString(mig.Version)).
+		Arg("namespace", mig.Namespace).
+		Arg("progress", mig.Progress)
+}
+
+func (s DefaultStatements) createHistoryTable(version int) *Statement {
+	columns := []string{
+		HistoryColumnName + " " + s.varcharType(512) + " not null",
+		HistoryColumnStartedAt + " " + s.timestampType() + " not null",
+		HistoryColumnDuration + " " + s.integerType() + " not null",
+		HistoryColumnChecksum + " char(43) not null",
+		HistoryColumnStatus + " " + s.varcharType(16) + " not null",
+		HistoryColumnError + " " + s.varcharType(HistoryErrorMaxLength),
+		HistoryColumnRank + " " + s.integerType() + " not null",
+		HistoryColumnDesc + " " + s.varcharType(512),
+		HistoryColumnHostname + " " + s.varcharType(128),
+		HistoryColumnPid + " " + s.integerType(),
+		HistoryColumnOsUser + " " + s.varcharType(128),
+		HistoryColumnDBUser + " " + s.varcharType(128),
+		HistoryColumnVersion + " " + s.varcharType(128),
+		HistoryColumnNamespace + " " + s.varcharType(128) + " not null",
+	}
+
+	if version >= 3 {
+		columns = append(columns, HistoryColumnProgress+" "+s.integerType())
+	}
+
+	return NewStatement(
+		`create table %s (
+			%s,
+			constraint %s primary key (%s, %s)
+		)`,
+		s.qualifiedHistoryTable(),
+		strings.Join(columns, ",\n\t\t\t"),
+		s.quotes.Quote(s.historyTable()+"_pk"),
+		HistoryColumnNamespace,
+		HistoryColumnName,
+	)
+}
+
+func (s DefaultStatements) addHistoryColumn(column, columnType string) *Statement {
+	stmt := NewStatement("alter table %s add %s %s", s.qualifiedHistoryTable(), column, columnType)
+	stmt.column = column
+
+	return stmt
+}
+
+func (s DefaultStatements) HistoryBackupTable() string {
+	return s.historyTable() + "_v1"
+}
+
+func (s DefaultStatements) RestoreHistory(dropHistory bool) []*Statement {
+	backup := s.qualify(s.HistoryBackupTable())
+	columns := strings.Join(historyColumnsV1(), ", ")
+	result := make([]*Statement, 0, 4)
+
+	if dropHistory {
+		result = append(result, NewStatement("drop table %s", s.qualifiedHistoryTable()))
+	}
+
+	return append(result,
+		s.createHistoryTable(2),
+		NewStatement(
+			"insert into %s (%s, %s) select %s, '%s' from %s",
+			s.qualifiedHistoryTable(),
+			columns,
+			HistoryColumnNamespace,
+			columns,
+			DefaultNamespace,
+			backup,
+		),
+		NewStatement("drop table %s", backup),
+	)
+}
+
+func (s DefaultStatements) rebuildHistory(copyHistory *Statement) []*Statement {
+	return append([]*Statement{copyHistory}, s.RestoreHistory(true)...)
 }
 
 func (s DefaultStatements) String() string {
-	return "Default SQL statements"
+	dialect := valueOrDefault(s.dialect, "Default")
+
+	if s.schema == "" && s.history == "" && s.lock == "" && s.metadata == "" {
+		return dialect + " SQL statements"
+	}
+
+	return fmt.Sprintf(
+		"%s SQL statements on %s, %s and %s",
+		dialect,
+		s.qualifiedHistoryTable(),
+		s.qualifiedLockTable(),
+		s.qualifiedMetadataTable(),
+	)
+}
+
+func (s DefaultStatements) historyTable() string {
+	return valueOrDefault(s.history, HistoryTableName)
+}
+
+func (s DefaultStatements) lockTable() string {
+	return valueOrDefault(s.lock, LockTableName)
+}
+
+func (s DefaultStatements) metadataTable() string {
+	return valueOrDefault(s.metadata, MetadataTableName)
+}
+
+func (s DefaultStatements) integerType() string {
+	return valueOrDefault(s.types.integer, "int")
+}
+
+func (s DefaultStatements) timestampType() string {
+	return valueOrDefault(s.types.timestamp, "timestamp")
+}
+
+func (s DefaultStatements) varcharType(length int) string {
+	return fmt.Sprintf("%s(%d)", valueOrDefault(s.types.varchar, "varchar"), length)
+}
+
+func (s DefaultStatements) qualifiedHistoryTable() string {
+	return s.qualify(s.historyTable())
+}
+
+func (s DefaultStatements) qualifiedLockTable() string {
+	return s.qualify(s.lockTable())
+}
+
+func (s DefaultStatements) qualifiedMetadataTable() string {
+	return s.qualify(s.metadataTable())
+}
+
+func (s DefaultStatements) qualify(table string) string {
+	if s.schema == "" {
+		return s.quotes.Quote(table)
+	}
+
+	return s.quotes.Quote(s.schema) + "." + s.quotes.Quote(table)
+}
+
+func informationSchemaTableExists(currentSchema, schema, table string) *Statement {
+	if schema == "" {
+		return NewStatement(
+			"select count(1) from information_schema.tables where table_schema = %s and table_name = :table",
+			currentSchema,
+		).
+			Arg("table", table)
+	}
+
+	return NewStatement(
+		"select count(1) from information_schema.tables where table_schema = :schema and table_name = :table",
+	).
+		Arg("schema", schema).
+		Arg("table", table)
+}
+
+func historyColumnsV1() []string {
+	return []string{
+		HistoryColumnName,
+		HistoryColumnStartedAt,
+		HistoryColumnDuration,
+		HistoryColumnChecksum,
+		HistoryColumnStatus,
+		HistoryColumnError,
+		HistoryColumnRank,
+		HistoryColumnDesc,
+		HistoryColumnHostname,
+		HistoryColumnPid,
+		HistoryColumnOsUser,
+		HistoryColumnDBUser,
+		HistoryColumnVersion,
+	}
+}
+
+func truncate(s string, maxLength int) string {
+	runes := []rune(s)
+
+	if len(runes) <= maxLength {
+		return s
+	}
+
+	return string(runes[:maxLength])
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
 }
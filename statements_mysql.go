@@ -0,0 +1,21 @@
+package dejavu
+
+type MySQLStatements struct {
+	DefaultStatements
+}
+
+func NewMySQLStatements(stmts DefaultStatements) MySQLStatements {
+	stmts.dialect = "MySQL"
+	stmts.quotes = QuotesMySQL()
+	stmts.types.timestamp = "datetime(6)"
+
+	return MySQLStatements{DefaultStatements: stmts}
+}
+
+func (s MySQLStatements) SplitScript(content string) []*Statement {
+	return newStatements(splitStatements(content, true))
+}
+
+func (s MySQLStatements) TableExists(table string) *Statement {
+	return informationSchemaTableExists("database()", s.schema, table)
+}
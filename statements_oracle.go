@@ -0,0 +1,75 @@
+package dejavu
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	oracleBlockTerminator = regexp.MustCompile(`(?m)^[ \t]*/[ \t]*$`)
+	oraclePLSQLBlock      = regexp.MustCompile(
+		`(?is)^\s*(declare|begin|create\s+(or\s+replace\s+)?((non)?editionable\s+)?(function|package|procedure|trigger|type))\b`,
+	)
+)
+
+type OracleStatements struct {
+	DefaultStatements
+}
+
+func NewOracleStatements(stmts DefaultStatements) OracleStatements {
+	stmts.dialect = "Oracle"
+	stmts.quotes = QuotesOracle()
+	stmts.types = columnTypes{
+		integer: "number(10)",
+		varchar: "varchar2",
+	}
+
+	return OracleStatements{DefaultStatements: stmts}
+}
+
+func (s OracleStatements) TableExists(table string) *Statement {
+	if s.schema == "" {
+		return NewStatement("select count(1) from user_tables where table_name = :table").
+			Arg("table", table)
+	}
+
+	return NewStatement("select count(1) from all_tables where owner = :schema and table_name = :table").
+		Arg("schema", s.schema).
+		Arg("table", table)
+}
+
+func (s OracleStatements) CurrentTimestamp() *Statement {
+	return NewStatement("select current_timestamp from dual")
+}
+
+func (s OracleStatements) CurrentUser() *Statement {
+	return NewStatement("select user from dual")
+}
+
+func (s OracleStatements) Script(content string) []*Statement {
+	blocks := oracleBlockTerminator.Split(content, -1)
+	result := make([]*Statement, 0, len(blocks))
+
+	for _, block := range blocks {
+		rest := block
+
+		for _, stmt := range splitStatements(block, false) {
+			idx := strings.Index(rest, stmt)
+
+			if oraclePLSQLBlock.MatchString(trimLeadingComments(stmt)) {
+				result = append(result, NewStatement("%s", strings.TrimSpace(rest[idx:])))
+
+				break
+			}
+
+			result = append(result, NewStatement("%s", stmt))
+			rest = rest[idx+len(stmt):]
+		}
+	}
+
+	return result
+}
+
+func (s OracleStatements) SplitScript(content string) []*Statement {
+	return s.Script(content)
+}
@@ -0,0 +1,17 @@
+package dejavu
+
+type PostgreSQLStatements struct {
+	DefaultStatements
+}
+
+func NewPostgreSQLStatements(stmts DefaultStatements) PostgreSQLStatements {
+	stmts.dialect = "PostgreSQL"
+	stmts.quotes = QuotesPostgreSQL()
+	stmts.types.timestamp = "timestamptz"
+
+	return PostgreSQLStatements{DefaultStatements: stmts}
+}
+
+func (s PostgreSQLStatements) TableExists(table string) *Statement {
+	return informationSchemaTableExists("current_schema()", s.schema, table)
+}
@@ -0,0 +1,24 @@
+package dejavu
+
+type SQLiteStatements struct {
+	DefaultStatements
+}
+
+func NewSQLiteStatements(stmts DefaultStatements) SQLiteStatements {
+	stmts.dialect = "SQLite"
+	stmts.quotes = QuotesSQLite()
+
+	return SQLiteStatements{DefaultStatements: stmts}
+}
+
+func (s SQLiteStatements) TableExists(table string) *Statement {
+	return NewStatement(
+		"select count(1) from %s where type = 'table' and name = :table",
+		s.qualify("sqlite_master"),
+	).
+		Arg("table", table)
+}
+
+func (s SQLiteStatements) CurrentUser() *Statement {
+	return NewStatement("select null")
+}
@@ -0,0 +1,60 @@
+package dejavu
+
+import (
+	"regexp"
+	"strings"
+)
+
+var sqlServerBatchSeparator = regexp.MustCompile(`(?im)^[ \t]*go[ \t]*;?[ \t]*$`)
+
+type SQLServerStatements struct {
+	DefaultStatements
+}
+
+func NewSQLServerStatements(stmts DefaultStatements) SQLServerStatements {
+	stmts.dialect = "SQL Server"
+	stmts.quotes = QuotesSQLServer()
+	stmts.types = columnTypes{
+		timestamp: "datetime2",
+		varchar:   "nvarchar",
+	}
+
+	return SQLServerStatements{DefaultStatements: stmts}
+}
+
+func (s SQLServerStatements) ReadOnlyTransactions() bool {
+	return false
+}
+
+func (s SQLServerStatements) TableExists(table string) *Statement {
+	return informationSchemaTableExists("schema_name()", s.schema, table)
+}
+
+func (s SQLServerStatements) UpgradeMetadata(from int) []*Statement {
+	if from != 1 {
+		return s.DefaultStatements.UpgradeMetadata(from)
+	}
+
+	return s.rebuildHistory(NewStatement(
+		"select * into %s from %s",
+		s.qualify(s.HistoryBackupTable()),
+		s.qualifiedHistoryTable(),
+	))
+}
+
+func (s SQLServerStatements) Script(content string) []*Statement {
+	batches := sqlServerBatchSeparator.Split(content, -1)
+	result := make([]*Statement, 0, len(batches))
+
+	for _, batch := range batches {
+		if strings.TrimSpace(batch) != "" {
+			result = append(result, NewStatement("%s", batch))
+		}
+	}
+
+	return result
+}
+
+func (s SQLServerStatements) SplitScript(content string) []*Statement {
+	return s.Script(content)
+}
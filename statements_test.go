@@ -0,0 +1,361 @@
+package dejavu
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func goldenStatements(stmts Statements) map[string][]*Statement {
+	lck := Lock{id: lockID, namespace: DefaultNamespace, hostname: "host", pid: 42, token: "token"}
+	mig := Migration{
+		Name:          "2023-01-01/01_create_table.sql",
+		Start:         time.Date(2023, time.March, 10, 22, 4, 27, 0, time.UTC),
+		DurationMs:    12,
+		Checksum:      "checksum",
+		Namespace:     DefaultNamespace,
+		InstalledRank: 1,
+		Description:   "create table",
+		Hostname:      "host",
+		Pid:           42,
+	}
+
+	return map[string][]*Statement{
+		"create_history_table":  {stmts.CreateHistoryTable()},
+		"create_lock_table":     {stmts.CreateLockTable()},
+		"create_metadata_table": {stmts.CreateMetadataTable()},
+		"upgrade_lock_table":    stmts.UpgradeLockTable(),
+		"upgrade_metadata_0":    stmts.UpgradeMetadata(0),
+		"upgrade_metadata_1":    stmts.UpgradeMetadata(1),
+		"upgrade_metadata_2":    stmts.UpgradeMetadata(2),
+		"restore_history":       stmts.RestoreHistory(false),
+		"table_exists":          {stmts.TableExists(HistoryTableName)},
+		"current_timestamp":     {stmts.CurrentTimestamp()},
+		"current_user":          {stmts.CurrentUser()},
+		"lock":                  {stmts.Lock(lck)},
+		"read_lock":             {stmts.ReadLock(lck)},
+		"refresh_lock":          {stmts.RefreshLock(lck)},
+		"expire_lock":           {stmts.ExpireLock(lck, mig.Start)},
+		"unlock":                {stmts.Unlock(lck)},
+		"history":               {stmts.History(DefaultNamespace)},
+		"log":                   {stmts.Log(mig)},
+		"update_progress":       {stmts.UpdateProgress(mig)},
+		"rename_migration":      {stmts.RenameMigration(mig, "01_create_table.sql")},
+		"max_installed_rank":    {stmts.MaxInstalledRank()},
+	}
+}
+
+func assertGolden(t *testing.T, dir string, stmts Statements, placeholders Placeholders) {
+	t.Helper()
+
+	for name, list := range goldenStatements(stmts) {
+		t.Run(name, func(t *testing.T) {
+			sb := strings.Builder{}
+
+			for _, stmt := range list {
+				query, args := stmt.WithPlaceholders(placeholders)
+
+				sb.WriteString(query)
+				sb.WriteString("\n")
+
+				for i, arg := range args {
+					sb.WriteString(fmt.Sprintf("-- %d: %v\n", i+1, arg))
+				}
+
+				sb.WriteString("\n")
+			}
+
+			path := filepath.Join("testdata", "golden", dir, name+".sql")
+
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(sb.String()), 0o600))
+			}
+
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), sb.String())
+		})
+	}
+}
+
+func TestDefaultStatements_Tables(t *testing.T) {
+	tests := []struct {
+		name     string
+		stmts    DefaultStatements
+		history  string
+		lock     string
+		metadata string
+	}{
+		{
+			name:     "default",
+			stmts:    DefaultStatements{},
+			history:  "deja_vu_history",
+			lock:     "deja_vu_lock",
+			metadata: "deja_vu_metadata",
+		},
+		{
+			name: "custom",
+			stmts: DefaultStatements{}.
+				WithHistoryTable("billing_history").
+				WithLockTable("billing_lock").
+				WithMetadataTable("billing_metadata"),
+			history:  "billing_history",
+			lock:     "billing_lock",
+			metadata: "billing_metadata",
+		},
+		{
+			name:     "schema",
+			stmts:    DefaultStatements{}.WithSchema("meta").WithQuotes(QuotesPostgreSQL()),
+			history:  `"meta"."deja_vu_history"`,
+			lock:     `"meta"."deja_vu_lock"`,
+			metadata: `"meta"."deja_vu_metadata"`,
+		},
+		{
+			name:     "quotes",
+			stmts:    DefaultStatements{}.WithSchema("billing").WithQuotes(QuotesMySQL()),
+			history:  "`billing`.`deja_vu_history`",
+			lock:     "`billing`.`deja_vu_lock`",
+			metadata: "`billing`.`deja_vu_metadata`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.history, tt.stmts.qualifiedHistoryTable())
+			assert.Equal(t, tt.lock, tt.stmts.qualifiedLockTable())
+			assert.Equal(t, tt.metadata, tt.stmts.qualifiedMetadataTable())
+		})
+	}
+}
+
+func TestDefaultStatements_CreateLockTable(t *testing.T) {
+	stmt := DefaultStatements{}.WithSchema("meta").WithQuotes(QuotesPostgreSQL()).CreateLockTable()
+
+	assert.Contains(t, stmt.sql, `create table "meta"."deja_vu_lock" (`)
+	assert.Contains(t, stmt.sql, `constraint "deja_vu_lock_pk" primary key (id)`)
+}
+
+func TestDefaultStatements_String(t *testing.T) {
+	assert.Equal(t, "Default SQL statements", DefaultStatements{}.String())
+	assert.Equal(
+		t,
+		"Default SQL statements on meta.deja_vu_history, meta.deja_vu_lock and meta.deja_vu_metadata",
+		DefaultStatements{}.WithSchema("meta").String(),
+	)
+}
+
+func TestDialectStatements_TableExists(t *testing.T) {
+	tests := []struct {
+		name  string
+		stmts Statements
+		sql   string
+		args  int
+	}{
+		{
+			name:  "default",
+			stmts: DefaultStatements{},
+		},
+		{
+			name:  "mysql",
+			stmts: NewMySQLStatements(DefaultStatements{}),
+			sql:   "select count(1) from information_schema.tables where table_schema = database() and table_name = :table",
+			args:  1,
+		},
+		{
+			name:  "postgresql",
+			stmts: NewPostgreSQLStatements(DefaultStatements{}.WithSchema("meta")),
+			sql:   "select count(1) from information_schema.tables where table_schema = :schema and table_name = :table",
+			args:  2,
+		},
+		{
+			name:  "sqlite",
+			stmts: NewSQLiteStatements(DefaultStatements{}.WithSchema("main")),
+			sql:   `select count(1) from "main"."sqlite_master" where type = 'table' and name = :table`,
+			args:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := tt.stmts.TableExists("deja_vu_history")
+
+			if tt.sql == "" {
+				assert.Nil(t, stmt)
+
+				return
+			}
+
+			require.NotNil(t, stmt)
+			assert.Equal(t, tt.sql, stmt.sql)
+			assert.Len(t, stmt.args, tt.args)
+		})
+	}
+}
+
+func TestDialectStatements_CreateHistoryTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		stmts     Statements
+		table     string
+		timestamp string
+	}{
+		{
+			name:      "default",
+			stmts:     DefaultStatements{},
+			table:     "deja_vu_history",
+			timestamp: "started_at timestamp not null",
+		},
+		{
+			name:      "mysql",
+			stmts:     NewMySQLStatements(DefaultStatements{}),
+			table:     "`deja_vu_history`",
+			timestamp: "started_at datetime(6) not null",
+		},
+		{
+			name:      "postgresql",
+			stmts:     NewPostgreSQLStatements(DefaultStatements{}),
+			table:     `"deja_vu_history"`,
+			timestamp: "started_at timestamptz not null",
+		},
+		{
+			name:      "sqlite",
+			stmts:     NewSQLiteStatements(DefaultStatements{}),
+			table:     `"deja_vu_history"`,
+			timestamp: "started_at timestamp not null",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := tt.stmts.CreateHistoryTable()
+
+			assert.Contains(t, stmt.sql, "create table "+tt.table+" (")
+			assert.Contains(t, stmt.sql, tt.timestamp)
+		})
+	}
+}
+
+func TestDialectStatements_String(t *testing.T) {
+	assert.Equal(t, "PostgreSQL SQL statements", NewPostgreSQLStatements(DefaultStatements{}).String())
+	assert.Equal(
+		t,
+		"MySQL SQL statements on `billing`.`deja_vu_history`, `billing`.`deja_vu_lock` and `billing`.`deja_vu_metadata`",
+		NewMySQLStatements(DefaultStatements{}.WithSchema("billing")).String(),
+	)
+}
+
+func TestSQLServerStatements_Golden(t *testing.T) {
+	assertGolden(t, "sqlserver", NewSQLServerStatements(DefaultStatements{}), PlaceholdersSQLServer())
+}
+
+func TestSQLServerStatements_Script(t *testing.T) {
+	stmts := NewSQLServerStatements(DefaultStatements{}).Script(`create table country (name nvarchar(64) not null);
+GO
+create view country_names as select name from country;
+  go  
+insert into country values ('Gogo');
+`)
+
+	require.Len(t, stmts, 3)
+	assert.Equal(t, "create table country (name nvarchar(64) not null);\n", stmts[0].sql)
+	assert.Equal(t, "\ncreate view country_names as select name from country;\n", stmts[1].sql)
+	assert.Equal(t, "\ninsert into country values ('Gogo');\n", stmts[2].sql)
+}
+
+func TestFilterMigration_SQLServer(t *testing.T) {
+	assert.False(t, FilterMigration("01_create_table.sqlserver.sql", "sqlserver"))
+	assert.True(t, FilterMigration("01_create_table.sqlserver.sql", "postgresql"))
+}
+
+func TestOracleStatements_Golden(t *testing.T) {
+	assertGolden(t, "oracle", NewOracleStatements(DefaultStatements{}), PlaceholdersOracle())
+}
+
+func TestOracleStatements_Script(t *testing.T) {
+	stmts := NewOracleStatements(DefaultStatements{}).Script(`create table country (name varchar2(64) not null);
+/
+create or replace procedure add_country(p_name varchar2) as
+begin
+  insert into country values (p_name);
+end;
+/
+begin
+  add_country('France');
+end;
+/
+insert into country values ('Italy')
+`)
+
+	require.Len(t, stmts, 4)
+	assert.Equal(t, "create table country (name varchar2(64) not null)", stmts[0].sql)
+	assert.True(t, strings.HasSuffix(stmts[1].sql, "end;"))
+	assert.Equal(t, "begin\n  add_country('France');\nend;", stmts[2].sql)
+	assert.Equal(t, "insert into country values ('Italy')", stmts[3].sql)
+}
+
+func TestSQLServerStatements_SplitScript(t *testing.T) {
+	stmts := NewSQLServerStatements(DefaultStatements{}).SplitScript(
+		"create procedure p as begin select 1; select 2; end\nGO\ninsert into t values (1)\n",
+	)
+
+	require.Len(t, stmts, 2)
+	assert.Equal(t, "create procedure p as begin select 1; select 2; end\n", stmts[0].sql)
+	assert.Equal(t, "\ninsert into t values (1)\n", stmts[1].sql)
+}
+
+func TestMySQLStatements_SplitScript(t *testing.T) {
+	stmts := NewMySQLStatements(DefaultStatements{}).SplitScript("insert into t values ('it\\'s; fine');\nselect 1;")
+
+	require.Len(t, stmts, 2)
+	assert.Equal(t, "insert into t values ('it\\'s; fine')", stmts[0].sql)
+	assert.Equal(t, "select 1", stmts[1].sql)
+}
+
+func TestOracleStatements_Script_LeadingComment(t *testing.T) {
+	stmts := NewOracleStatements(DefaultStatements{}).Script("-- header\nbegin\n null;\n null;\nend;\n/\n" +
+		"/* add a row */ insert into a values (1);\n")
+
+	require.Len(t, stmts, 2)
+	assert.Equal(t, "-- header\nbegin\n null;\n null;\nend;", stmts[0].sql)
+	assert.Equal(t, "/* add a row */ insert into a values (1)", stmts[1].sql)
+}
+
+func TestOracleStatements_Script_Mixed(t *testing.T) {
+	stmts := NewOracleStatements(DefaultStatements{}).Script(`create table a (id int);
+create table b (name varchar2(64) default 'x;y');
+insert into b values ('z');
+create or replace procedure add_b(p_name varchar2) as
+begin
+  insert into b values (p_name);
+end;
+/
+create table c (id int);
+create table d (id int);
+`)
+
+	require.Len(t, stmts, 6)
+	assert.Equal(t, "create table a (id int)", stmts[0].sql)
+	assert.Equal(t, "create table b (name varchar2(64) default 'x;y')", stmts[1].sql)
+	assert.Equal(t, "insert into b values ('z')", stmts[2].sql)
+	assert.Equal(t, `create or replace procedure add_b(p_name varchar2) as
+begin
+  insert into b values (p_name);
+end;`, stmts[3].sql)
+	assert.Equal(t, "create table c (id int)", stmts[4].sql)
+	assert.Equal(t, "create table d (id int)", stmts[5].sql)
+}
+
+func TestFilterMigration_Oracle(t *testing.T) {
+	assert.False(t, FilterMigration("01_create_table.oracle.sql", "oracle"))
+	assert.True(t, FilterMigration("01_create_table.oracle.sql", "sqlserver"))
+}
@@ -0,0 +1,9 @@
+create table "deja_vu_lock" (
+			id number(10) not null,
+			hostname varchar2(128) not null,
+			pid number(10) not null,
+			since timestamp not null,
+			token varchar2(64),
+			constraint "deja_vu_lock_pk" primary key (id)
+		)
+
@@ -0,0 +1,2 @@
+select user from dual
+
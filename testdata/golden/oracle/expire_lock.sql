@@ -0,0 +1,4 @@
+delete from "deja_vu_lock" where id = :1 and since < :2
+-- 1: 1
+-- 2: 2023-03-10 22:04:27 +0000 UTC
+
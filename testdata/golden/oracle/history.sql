@@ -0,0 +1,3 @@
+select name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, progress from "deja_vu_history" where namespace = :1 order by installed_rank
+-- 1: default
+
@@ -0,0 +1,6 @@
+insert into "deja_vu_lock" (id, hostname, pid, since, token) values (:1, :2, :3, current_timestamp, :4)
+-- 1: 1
+-- 2: host
+-- 3: 42
+-- 4: token
+
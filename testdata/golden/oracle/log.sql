@@ -0,0 +1,19 @@
+insert into "deja_vu_history" (name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, namespace, progress)
+		values (:1, :2, :3, :4, :5, :6, :7,
+			:8, :9, :10, :11, :12, :13, :14, :15)
+-- 1: 2023-01-01/01_create_table.sql
+-- 2: 2023-03-10 22:04:27 +0000 UTC
+-- 3: 12
+-- 4: checksum
+-- 5: succeeded
+-- 6: { false}
+-- 7: 1
+-- 8: {create table true}
+-- 9: {host true}
+-- 10: 42
+-- 11: { false}
+-- 12: { false}
+-- 13: { false}
+-- 14: default
+-- 15: 0
+
@@ -0,0 +1,2 @@
+select coalesce(max(installed_rank), 0) from "deja_vu_history"
+
@@ -0,0 +1,3 @@
+select hostname, pid, since from "deja_vu_lock" where id = :1
+-- 1: 1
+
@@ -0,0 +1,4 @@
+update "deja_vu_lock" set since = current_timestamp where id = :1 and token = :2
+-- 1: 1
+-- 2: token
+
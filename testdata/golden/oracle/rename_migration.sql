@@ -0,0 +1,5 @@
+update "deja_vu_history" set name = :1 where namespace = :2 and name = :3
+-- 1: 01_create_table.sql
+-- 2: default
+-- 3: 2023-01-01/01_create_table.sql
+
@@ -0,0 +1,22 @@
+create table "deja_vu_history" (
+			name varchar2(512) not null,
+			started_at timestamp not null,
+			duration_ms number(10) not null,
+			checksum char(43) not null,
+			status varchar2(16) not null,
+			error_message varchar2(1024),
+			installed_rank number(10) not null,
+			description varchar2(512),
+			hostname varchar2(128),
+			pid number(10),
+			os_user varchar2(128),
+			db_user varchar2(128),
+			version varchar2(128),
+			namespace varchar2(128) not null,
+			constraint "deja_vu_history_pk" primary key (namespace, name)
+		)
+
+insert into "deja_vu_history" (name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, namespace) select name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, 'default' from "deja_vu_history_v1"
+
+drop table "deja_vu_history_v1"
+
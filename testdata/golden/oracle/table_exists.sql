@@ -0,0 +1,3 @@
+select count(1) from user_tables where table_name = :1
+-- 1: deja_vu_history
+
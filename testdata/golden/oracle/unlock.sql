@@ -0,0 +1,4 @@
+delete from "deja_vu_lock" where id = :1 and token = :2
+-- 1: 1
+-- 2: token
+
@@ -0,0 +1,10 @@
+update "deja_vu_history" set status = :1, duration_ms = :2, checksum = :3, error_message = :4, progress = :5
+		where namespace = :6 and name = :7
+-- 1: 
+-- 2: 12
+-- 3: checksum
+-- 4: { false}
+-- 5: 0
+-- 6: default
+-- 7: 2023-01-01/01_create_table.sql
+
@@ -0,0 +1,18 @@
+alter table "deja_vu_history" add status varchar2(16)
+
+alter table "deja_vu_history" add error_message varchar2(1024)
+
+alter table "deja_vu_history" add installed_rank number(10)
+
+alter table "deja_vu_history" add description varchar2(512)
+
+alter table "deja_vu_history" add hostname varchar2(128)
+
+alter table "deja_vu_history" add pid number(10)
+
+alter table "deja_vu_history" add os_user varchar2(128)
+
+alter table "deja_vu_history" add db_user varchar2(128)
+
+alter table "deja_vu_history" add version varchar2(128)
+
@@ -0,0 +1,2 @@
+alter table "deja_vu_history" add progress number(10)
+
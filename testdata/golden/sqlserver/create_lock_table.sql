@@ -0,0 +1,9 @@
+create table [deja_vu_lock] (
+			id int not null,
+			hostname nvarchar(128) not null,
+			pid int not null,
+			since datetime2 not null,
+			token nvarchar(64),
+			constraint [deja_vu_lock_pk] primary key (id)
+		)
+
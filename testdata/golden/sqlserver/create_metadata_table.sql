@@ -0,0 +1,4 @@
+create table [deja_vu_metadata] (
+			version int not null
+		)
+
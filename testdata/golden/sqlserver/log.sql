@@ -0,0 +1,19 @@
+insert into [deja_vu_history] (name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, namespace, progress)
+		values (@p1, @p2, @p3, @p4, @p5, @p6, @p7,
+			@p8, @p9, @p10, @p11, @p12, @p13, @p14, @p15)
+-- 1: 2023-01-01/01_create_table.sql
+-- 2: 2023-03-10 22:04:27 +0000 UTC
+-- 3: 12
+-- 4: checksum
+-- 5: succeeded
+-- 6: { false}
+-- 7: 1
+-- 8: {create table true}
+-- 9: {host true}
+-- 10: 42
+-- 11: { false}
+-- 12: { false}
+-- 13: { false}
+-- 14: default
+-- 15: 0
+
@@ -0,0 +1,4 @@
+update [deja_vu_lock] set since = current_timestamp where id = @p1 and token = @p2
+-- 1: 1
+-- 2: token
+
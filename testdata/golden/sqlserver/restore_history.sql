@@ -0,0 +1,22 @@
+create table [deja_vu_history] (
+			name nvarchar(512) not null,
+			started_at datetime2 not null,
+			duration_ms int not null,
+			checksum char(43) not null,
+			status nvarchar(16) not null,
+			error_message nvarchar(1024),
+			installed_rank int not null,
+			description nvarchar(512),
+			hostname nvarchar(128),
+			pid int,
+			os_user nvarchar(128),
+			db_user nvarchar(128),
+			version nvarchar(128),
+			namespace nvarchar(128) not null,
+			constraint [deja_vu_history_pk] primary key (namespace, name)
+		)
+
+insert into [deja_vu_history] (name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, namespace) select name, started_at, duration_ms, checksum, status, error_message, installed_rank, description, hostname, pid, os_user, db_user, version, 'default' from [deja_vu_history_v1]
+
+drop table [deja_vu_history_v1]
+
@@ -0,0 +1,3 @@
+select count(1) from information_schema.tables where table_schema = schema_name() and table_name = @p1
+-- 1: deja_vu_history
+
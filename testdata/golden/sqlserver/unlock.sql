@@ -0,0 +1,4 @@
+delete from [deja_vu_lock] where id = @p1 and token = @p2
+-- 1: 1
+-- 2: token
+
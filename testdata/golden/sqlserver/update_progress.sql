@@ -0,0 +1,10 @@
+update [deja_vu_history] set status = @p1, duration_ms = @p2, checksum = @p3, error_message = @p4, progress = @p5
+		where namespace = @p6 and name = @p7
+-- 1: 
+-- 2: 12
+-- 3: checksum
+-- 4: { false}
+-- 5: 0
+-- 6: default
+-- 7: 2023-01-01/01_create_table.sql
+
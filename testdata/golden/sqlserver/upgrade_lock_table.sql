@@ -0,0 +1,2 @@
+alter table [deja_vu_lock] add token nvarchar(64)
+
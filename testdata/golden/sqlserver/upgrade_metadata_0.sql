@@ -0,0 +1,18 @@
+alter table [deja_vu_history] add status nvarchar(16)
+
+alter table [deja_vu_history] add error_message nvarchar(1024)
+
+alter table [deja_vu_history] add installed_rank int
+
+alter table [deja_vu_history] add description nvarchar(512)
+
+alter table [deja_vu_history] add hostname nvarchar(128)
+
+alter table [deja_vu_history] add pid int
+
+alter table [deja_vu_history] add os_user nvarchar(128)
+
+alter table [deja_vu_history] add db_user nvarchar(128)
+
+alter table [deja_vu_history] add version nvarchar(128)
+
@@ -0,0 +1,230 @@
+package dejavu
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	numericPrefix   = regexp.MustCompile(`^(\d+)`)
+	semanticPrefix  = regexp.MustCompile(`^[vV]?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:_|$)`)
+	timestampPrefix = regexp.MustCompile(`^(\d{14})(?:\D|$)`)
+)
+
+type Versioning interface {
+	fmt.Stringer
+
+	Parse(name string) (Version, error)
+}
+
+type Version struct {
+	ID         string
+	prefix     string
+	parts      []versionPart
+	prerelease []versionPart
+}
+
+type versionPart struct {
+	numeric bool
+	value   string
+}
+
+func (v Version) Compare(other Version) int {
+	if result := compareParts(v.parts, other.parts); result != 0 {
+		return result
+	}
+
+	switch {
+	case len(v.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+
+	return compareParts(v.prerelease, other.prerelease)
+}
+
+func (v Version) String() string {
+	return v.ID
+}
+
+func LexicalVersioning() Versioning {
+	return lexicalVersioning{}
+}
+
+func NumericVersioning() Versioning {
+	return numericVersioning{}
+}
+
+func TimestampVersioning() Versioning {
+	return timestampVersioning{}
+}
+
+func SemanticVersioning() Versioning {
+	return semanticVersioning{}
+}
+
+func DateFolderVersioning() Versioning {
+	return dateFolderVersioning{}
+}
+
+type lexicalVersioning struct{}
+
+func (v lexicalVersioning) Parse(name string) (Version, error) {
+	return Version{ID: name, parts: []versionPart{{value: name}}}, nil
+}
+
+func (v lexicalVersioning) String() string {
+	return "lexical versioning"
+}
+
+type numericVersioning struct{}
+
+func (v numericVersioning) Parse(name string) (Version, error) {
+	match := numericPrefix.FindString(path.Base(name))
+	if match == "" {
+		return Version{}, newError(nil, "missing numeric prefix in migration %s", name)
+	}
+
+	number := numericPart(match)
+
+	return Version{ID: number.value, prefix: match, parts: []versionPart{number}}, nil
+}
+
+func (v numericVersioning) String() string {
+	return "numeric versioning"
+}
+
+type timestampVersioning struct{}
+
+func (v timestampVersioning) Parse(name string) (Version, error) {
+	match := timestampPrefix.FindStringSubmatch(path.Base(name))
+	if match == nil {
+		return Version{}, newError(nil, "missing timestamp prefix in migration %s", name)
+	}
+
+	if _, err := time.Parse("20060102150405", match[1]); err != nil {
+		return Version{}, newError(err, "invalid timestamp prefix in migration %s", name)
+	}
+
+	return Version{ID: match[1], prefix: match[1], parts: []versionPart{numericPart(match[1])}}, nil
+}
+
+func (v timestampVersioning) String() string {
+	return "timestamp versioning"
+}
+
+type semanticVersioning struct{}
+
+func (v semanticVersioning) Parse(name string) (Version, error) {
+	match := semanticPrefix.FindStringSubmatch(trimExtension(path.Base(name)))
+	if match == nil {
+		return Version{}, newError(nil, "missing semantic version prefix in migration %s", name)
+	}
+
+	result := Version{
+		ID:     strings.Join(match[1:4], "."),
+		prefix: match[0],
+		parts: []versionPart{
+			numericPart(match[1]),
+			numericPart(match[2]),
+			numericPart(match[3]),
+		},
+	}
+
+	if match[4] != "" {
+		result.ID += "-" + match[4]
+
+		for _, identifier := range strings.Split(match[4], ".") {
+			result.prerelease = append(result.prerelease, parseVersionPart(identifier))
+		}
+	}
+
+	return result, nil
+}
+
+func (v semanticVersioning) String() string {
+	return "semantic versioning"
+}
+
+type dateFolderVersioning struct{}
+
+func (v dateFolderVersioning) Parse(name string) (Version, error) {
+	folder := path.Base(path.Dir(name))
+
+	date, err := time.Parse(time.DateOnly, folder)
+	if err != nil {
+		return Version{}, newError(err, "missing date folder for migration %s", name)
+	}
+
+	number, err := numericVersioning{}.Parse(name)
+	if err != nil {
+		return Version{}, err
+	}
+
+	return Version{
+		ID:     folder + "/" + number.ID,
+		prefix: number.prefix,
+		parts: []versionPart{
+			numericPart(date.Format("20060102")),
+			number.parts[0],
+		},
+	}, nil
+}
+
+func (v dateFolderVersioning) String() string {
+	return "date folder versioning"
+}
+
+func compareParts(left, right []versionPart) int {
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if result := left[i].compare(right[i]); result != 0 {
+			return result
+		}
+	}
+
+	return len(left) - len(right)
+}
+
+func (p versionPart) compare(other versionPart) int {
+	switch {
+	case p.numeric && other.numeric:
+		if len(p.value) != len(other.value) {
+			return len(p.value) - len(other.value)
+		}
+	case p.numeric:
+		return -1
+	case other.numeric:
+		return 1
+	}
+
+	return strings.Compare(p.value, other.value)
+}
+
+func parseVersionPart(value string) versionPart {
+	if numericPrefix.FindString(value) == value {
+		return numericPart(value)
+	}
+
+	return versionPart{value: value}
+}
+
+func numericPart(digits string) versionPart {
+	value := strings.TrimLeft(digits, "0")
+	if value == "" {
+		value = "0"
+	}
+
+	return versionPart{numeric: true, value: value}
+}
+
+func trimExtension(name string) string {
+	base, _ := dialectTags(name)
+
+	return strings.TrimSuffix(base, ".sql")
+}
@@ -0,0 +1,127 @@
+package dejavu
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersioning_Parse(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning Versioning
+		migration  string
+		want       string
+		wantErr    bool
+	}{
+		{name: "lexical", versioning: LexicalVersioning(), migration: "a/01_init.sql", want: "a/01_init.sql"},
+		{name: "numeric", versioning: NumericVersioning(), migration: "a/010_init.sql", want: "10"},
+		{name: "numeric zero", versioning: NumericVersioning(), migration: "000_init.sql", want: "0"},
+		{name: "numeric missing", versioning: NumericVersioning(), migration: "init.sql", wantErr: true},
+		{name: "timestamp", versioning: TimestampVersioning(), migration: "20230310220427_init.sql", want: "20230310220427"},
+		{name: "timestamp invalid", versioning: TimestampVersioning(), migration: "20231310220427_init.sql", wantErr: true},
+		{name: "timestamp short", versioning: TimestampVersioning(), migration: "202303102204_init.sql", wantErr: true},
+		{name: "semantic", versioning: SemanticVersioning(), migration: "V1.2.3__init.sql", want: "1.2.3"},
+		{name: "semantic tag", versioning: SemanticVersioning(), migration: "1.10.0.postgresql.sql", want: "1.10.0"},
+		{name: "semantic prerelease", versioning: SemanticVersioning(), migration: "1.0.0-rc.1_init.sql", want: "1.0.0-rc.1"},
+		{name: "semantic missing", versioning: SemanticVersioning(), migration: "1.0_init.sql", wantErr: true},
+		{name: "date folder", versioning: DateFolderVersioning(), migration: "2023-01-01/02_init.sql", want: "2023-01-01/2"},
+		{name: "date folder missing", versioning: DateFolderVersioning(), migration: "02_init.sql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := tt.versioning.Parse(tt.migration)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, version.String())
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning Versioning
+		lower      string
+		greater    string
+	}{
+		{name: "lexical", versioning: LexicalVersioning(), lower: "10_b.sql", greater: "2_a.sql"},
+		{name: "numeric", versioning: NumericVersioning(), lower: "2_b.sql", greater: "10_a.sql"},
+		{name: "timestamp", versioning: TimestampVersioning(), lower: "20230310220427_b.sql", greater: "20230310220428_a.sql"},
+		{name: "semantic", versioning: SemanticVersioning(), lower: "1.9.0_b.sql", greater: "1.10.0_a.sql"},
+		{name: "semantic prerelease", versioning: SemanticVersioning(), lower: "1.0.0-rc.1_b.sql", greater: "1.0.0_a.sql"},
+		{name: "semantic prerelease numeric", versioning: SemanticVersioning(), lower: "1.0.0-rc.2_b.sql", greater: "1.0.0-rc.10_a.sql"},
+		{name: "semantic prerelease alpha", versioning: SemanticVersioning(), lower: "1.0.0-1_b.sql", greater: "1.0.0-alpha_a.sql"},
+		{name: "date folder", versioning: DateFolderVersioning(), lower: "2023-01-01/10_b.sql", greater: "2023-01-02/01_a.sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, err := tt.versioning.Parse(tt.lower)
+			require.NoError(t, err)
+
+			greater, err := tt.versioning.Parse(tt.greater)
+			require.NoError(t, err)
+
+			assert.Negative(t, lower.Compare(greater))
+			assert.Positive(t, greater.Compare(lower))
+			assert.Zero(t, lower.Compare(lower))
+		})
+	}
+}
+
+func TestFsMigrations_List_Versioning(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_init.sql":         {Data: []byte("select 1;")},
+		"2_more.sql":         {Data: []byte("select 2;")},
+		"10_last.sqlite.sql": {Data: []byte("select 10;")},
+	}
+
+	names, err := NewFsMigrations(fsys).List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10_last.sqlite.sql", "1_init.sql", "2_more.sql"}, names)
+
+	names, err = NewFsMigrations(fsys).WithVersioning(NumericVersioning()).List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1_init.sql", "2_more.sql", "10_last.sqlite.sql"}, names)
+
+	fsys["01_duplicate.sql"] = &fstest.MapFile{Data: []byte("select 1;")}
+
+	_, err = NewFsMigrations(fsys).WithVersioning(NumericVersioning()).List("sqlite")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate version 1 for migrations")
+}
+
+func TestFsMigrations_List_SemanticVersioning(t *testing.T) {
+	fsys := fstest.MapFS{
+		"V1.10.0__later.sql":         {Data: []byte("select 3;")},
+		"V1.2.0__init.sql":           {Data: []byte("select 1;")},
+		"V1.2.1__fix.postgresql.sql": {Data: []byte("select 2;")},
+		"V1.2.1__fix.sqlite.sql":     {Data: []byte("select 2;")},
+	}
+
+	names, err := NewFsMigrations(fsys).WithVersioning(SemanticVersioning()).List("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"V1.2.0__init.sql", "V1.2.1__fix.sqlite.sql", "V1.10.0__later.sql"}, names)
+}
+
+func TestFilterMigration_Versions(t *testing.T) {
+	assert.False(t, FilterMigration("V1.2.3__init.sql", "sqlite"))
+	assert.False(t, FilterMigration("1.2.3", "sqlite"))
+	assert.False(t, FilterMigration("1.2.3.sqlite.sql", "sqlite"))
+	assert.True(t, FilterMigration("1.2.3.mysql.sql", "sqlite"))
+}
+
+func TestSemanticVersioning_DialectTags(t *testing.T) {
+	version, err := SemanticVersioning().Parse("1.2.3.mysql.postgresql.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", version.ID)
+}